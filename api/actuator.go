@@ -1,21 +1,101 @@
 package api
 
 import (
+	"github.com/angel-one/go-utils/log"
 	"github.com/gin-gonic/gin"
 	goActuator "github.com/sinhashubham95/go-actuator"
 	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	"github.com/sinhashubham95/go-example-project/utils/database"
 	"github.com/sinhashubham95/go-example-project/utils/flags"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"net/http"
+	"sync"
 )
 
 var (
-	actuatorHandler = goActuator.GetActuatorHandler(&goActuator.Config{
-		Env:     flags.Env(),
-		Name:    constants.ApplicationName,
-		Port:    flags.Port(),
-		Version: "",
-	})
+	// actuatorHandler is made on the first request rather than on the initialisation of the package, once the flags
+	// it is configured with are parsed, and without failing the package for the endpoints served here
+	actuatorHandler     http.HandlerFunc
+	actuatorHandlerOnce sync.Once
+
+	// these are the application specific endpoints served alongside the ones provided by the actuator
+	actuatorEndpoints = map[string]gin.HandlerFunc{
+		constants.StatsActuatorEndpoint:       stats,
+		constants.HealthActuatorEndpoint:      health,
+		constants.LivenessActuatorEndpoint:    liveness,
+		constants.ReadinessActuatorEndpoint:   readiness,
+		constants.CircuitsActuatorEndpoint:    circuits,
+		constants.ConfigPropsActuatorEndpoint: configProps,
+	}
 )
 
 func actuator(ctx *gin.Context) {
+	if handler, ok := actuatorEndpoints[ctx.Param("any")]; ok {
+		handler(ctx)
+		return
+	}
+	actuatorHandlerOnce.Do(func() {
+		actuatorHandler = goActuator.GetActuatorHandler(&goActuator.Config{
+			Env:     flags.Env(),
+			Name:    constants.ApplicationName,
+			Port:    flags.Port(),
+			Version: "",
+		})
+	})
 	actuatorHandler(ctx.Writer, ctx.Request)
 }
+
+func stats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, metrics.GetSnapshot())
+}
+
+// health reports the status of the application along with its dependencies, degraded while any of them is unreachable
+func health(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, getHealth())
+}
+
+// liveness reports the application as live as long as it responds, the dependencies being left to the readiness,
+// so that the application is not restarted for a database it cannot bring back
+func liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, models.HealthResponse{Status: constants.UpStatus})
+}
+
+// readiness reports the application as ready only when all its dependencies are reachable
+func readiness(ctx *gin.Context) {
+	response := getHealth()
+	if response.Status != constants.UpStatus {
+		ctx.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func getHealth() models.HealthResponse {
+	response := models.HealthResponse{
+		Status: constants.UpStatus,
+		Components: map[string]string{
+			constants.DatabaseConfig: constants.UpStatus,
+		},
+	}
+	if !database.IsAvailable() {
+		response.Status = constants.DegradedStatus
+		response.Components[constants.DatabaseConfig] = constants.DownStatus
+	}
+	return response
+}
+
+// configProps lists the effective properties of all the configs along with where they come from, the secrets redacted
+func configProps(ctx *gin.Context) {
+	properties, err := configs.GetProperties()
+	if err != nil {
+		log.Error(ctx).Err(err).Msg("unable to get the config properties")
+		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:        constants.InvalidConfigError,
+			Description: err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, properties)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"crypto/subtle"
+	"github.com/angel-one/go-utils/log"
+	"github.com/gin-gonic/gin"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	"github.com/sinhashubham95/go-example-project/utils/secrets"
+	"net/http"
+	"strings"
+)
+
+// requireAdmin rejects the admin requests not carrying the admin token as their bearer token
+// The token is resolved on every request so that a reloaded or rotated token applies right away, and every request is
+// rejected while there is no token to compare against.
+func requireAdmin(ctx *gin.Context) {
+	token, err := secrets.Resolve(configs.GetConfig().Application.Admin.Token)
+	if err != nil {
+		log.Error(ctx).Err(err).Msg("unable to resolve the admin token")
+	}
+	if err != nil || token == "" {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+			Code:        constants.AdminDisabledError,
+			Description: "admin api is disabled, no admin token configured",
+		})
+		return
+	}
+	prefix := constants.BearerTokenType + " "
+	authorization := ctx.GetHeader(constants.AuthorizationHeader)
+	if !strings.HasPrefix(authorization, prefix) ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(authorization, prefix)), []byte(token)) != 1 {
+		ctx.Header(constants.WWWAuthenticateHeader, constants.BearerTokenType)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+			Code:        constants.UnauthorizedError,
+			Description: "invalid or missing admin token",
+		})
+		return
+	}
+	ctx.Next()
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"github.com/angel-one/go-utils/log"
+	"github.com/gin-gonic/gin"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"net/http"
+)
+
+// circuits lists the circuit breakers of all the configured upstreams
+func circuits(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, httpclient.GetCircuits())
+}
+
+// forceOpenCircuit godoc
+// @Summary Forces the circuit of an upstream open
+// @Description Rejects all the requests to the upstream until its circuit is reset
+// @ID forceOpenCircuit
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Param name path string true "upstream name"
+// @Success 200 {object} httpclient.Circuit
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/circuits/{name}/open [post]
+func forceOpenCircuit(ctx *gin.Context) {
+	circuit, err := httpclient.ForceOpenCircuit(ctx, ctx.Param(constants.CircuitNameParam))
+	if err != nil {
+		sendCircuitError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, circuit)
+}
+
+// resetCircuit godoc
+// @Summary Resets the circuit of an upstream
+// @Description Closes the circuit of the upstream, whether it was forced open or opened by the failures
+// @ID resetCircuit
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Param name path string true "upstream name"
+// @Success 200 {object} httpclient.Circuit
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/circuits/{name}/reset [post]
+func resetCircuit(ctx *gin.Context) {
+	circuit, err := httpclient.ResetCircuit(ctx, ctx.Param(constants.CircuitNameParam))
+	if err != nil {
+		sendCircuitError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, circuit)
+}
+
+func sendCircuitError(ctx *gin.Context, err error) {
+	if errors.Is(err, httpclient.ErrUnknownRequest) {
+		ctx.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:        constants.UnknownUpstreamError,
+			Description: err.Error(),
+		})
+		return
+	}
+	log.Error(ctx).Stack().Err(err).Msg("unable to change the circuit")
+	ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Code:        constants.ExternalServiceFailureError,
+		Description: err.Error(),
+	})
+}
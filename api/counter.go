@@ -1,13 +1,17 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"github.com/angel-one/go-utils/log"
 	"github.com/gin-gonic/gin"
 	"github.com/sinhashubham95/go-example-project/business"
 	"github.com/sinhashubham95/go-example-project/constants"
 	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/database"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // createCounter godoc
@@ -19,7 +23,9 @@ import (
 // @Param key query string true "counter key"
 // @Success 201
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /counter/create [post]
 func createCounter(ctx *gin.Context) {
 	// get the key and validate
@@ -33,7 +39,7 @@ func createCounter(ctx *gin.Context) {
 	// now create a new counter
 	err = business.CreateCounter(ctx, key)
 	if err != nil {
-		sendCounterInternalServerError(ctx, err)
+		sendCounterError(ctx, err)
 		return
 	}
 
@@ -50,7 +56,9 @@ func createCounter(ctx *gin.Context) {
 // @Param key query string true "counter key"
 // @Success 200
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /counter/increment [put]
 func incrementCounter(ctx *gin.Context) {
 	// get the key and validate
@@ -64,7 +72,7 @@ func incrementCounter(ctx *gin.Context) {
 	// now increment the counter
 	err = business.IncrementCounter(ctx, key)
 	if err != nil {
-		sendCounterInternalServerError(ctx, err)
+		sendCounterError(ctx, err)
 	}
 }
 
@@ -77,7 +85,9 @@ func incrementCounter(ctx *gin.Context) {
 // @Param key query string true "counter key"
 // @Success 200
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /counter/decrement [put]
 func decrementCounter(ctx *gin.Context) {
 	// get the key and validate
@@ -91,7 +101,7 @@ func decrementCounter(ctx *gin.Context) {
 	// now decrement the counter
 	err = business.DecrementCounter(ctx, key)
 	if err != nil {
-		sendCounterInternalServerError(ctx, err)
+		sendCounterError(ctx, err)
 	}
 }
 
@@ -102,9 +112,13 @@ func decrementCounter(ctx *gin.Context) {
 // @Tags counter
 // @Produce  json
 // @Param key query string true "counter key"
+// @Param Cache-Control header string false "no-cache to bypass the cached count"
+// @Param X-Read-Your-Writes header bool false "true to read from the primary instead of the replicas"
 // @Success 200 {object} models.CounterResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /counter/current [get]
 func currentCount(ctx *gin.Context) {
 	// get the key and validate
@@ -115,9 +129,19 @@ func currentCount(ctx *gin.Context) {
 		return
 	}
 
-	count, err := business.CurrentCount(ctx, key)
+	// the cached count is skipped when the client explicitly asks for it
+	skipCache := strings.Contains(ctx.GetHeader(constants.CacheControlHeader), constants.NoCacheCacheControl)
+
+	// reading your own writes needs the primary, and a cache which may not have seen the write yet is skipped
+	var requestCtx context.Context = ctx
+	if readYourWrites, _ := strconv.ParseBool(ctx.GetHeader(constants.ReadYourWritesHeader)); readYourWrites {
+		requestCtx = database.WithPrimary(ctx)
+		skipCache = true
+	}
+
+	count, err := business.CurrentCount(requestCtx, key, skipCache)
 	if err != nil {
-		sendCounterInternalServerError(ctx, err)
+		sendCounterError(ctx, err)
 		return
 	}
 
@@ -127,6 +151,18 @@ func currentCount(ctx *gin.Context) {
 	})
 }
 
+// requireDatabase rejects the counter requests upfront while the database is unreachable
+func requireDatabase(ctx *gin.Context) {
+	if !database.IsAvailable() {
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Code:        constants.DatabaseUnavailableError,
+			Description: "database is unreachable, try again later",
+		})
+		return
+	}
+	ctx.Next()
+}
+
 func validateCounterKey(key string) error {
 	if key == "" {
 		return errors.New("invalid key provided, cannot be empty")
@@ -142,10 +178,24 @@ func sendCounterRequestValidationError(ctx *gin.Context, err error) {
 	})
 }
 
-func sendCounterInternalServerError(ctx *gin.Context, err error) {
-	log.Error(ctx).Stack().Err(err).Msg("unable to work with counter")
-	ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
-		Code:        constants.DatabaseFailureError,
-		Description: err.Error(),
-	})
+// sendCounterError responds with the status of the error, the missing and the existing counters being the client's
+func sendCounterError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, business.ErrCounterNotFound):
+		ctx.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:        constants.CounterNotFoundError,
+			Description: err.Error(),
+		})
+	case errors.Is(err, business.ErrCounterAlreadyExists):
+		ctx.JSON(http.StatusConflict, models.ErrorResponse{
+			Code:        constants.CounterAlreadyExistsError,
+			Description: err.Error(),
+		})
+	default:
+		log.Error(ctx).Stack().Err(err).Msg("unable to work with counter")
+		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:        constants.DatabaseFailureError,
+			Description: err.Error(),
+		})
+	}
 }
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"errors"
 	"github.com/angel-one/go-utils/log"
 	"github.com/gin-gonic/gin"
 	"github.com/sinhashubham95/go-example-project/business"
 	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/external/processor"
 	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
 	"net/http"
 )
 
@@ -16,19 +19,103 @@ import (
 // @Tags moxy
 // @Produce  json
 // @Success 200 {object} models.MoxyResponse
+// @Header 200 {string} X-Fallback "the fallback strategy when degraded data is served as the upstream failed"
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
 // @Router /moxy [get]
 func moxy(ctx *gin.Context) {
 	data, err := business.GetMoxy(ctx)
 	if err != nil {
 		log.Error(ctx).Stack().Err(err).Msg("error getting moxy")
+		sendUpstreamError(ctx, err)
+		return
+	}
+
+	// now handle success scenario, letting the clients know when they got degraded data
+	if data.Fallback != "" {
+		ctx.Header(constants.FallbackHeader, data.Fallback)
+	}
+	ctx.JSON(http.StatusOK, data)
+}
+
+// proxyMoxy godoc
+// @Summary Proxy the request to moxy
+// @Description Forwards the method, path, query, allowed headers and body of the request to moxy,
+// @Description streaming its response back with the status and headers, the hop-by-hop ones left out
+// @ID proxyMoxy
+// @Tags moxy
+// @Param path path string true "the path under the moxy url"
+// @Success 200 {string} string "the moxy response, with the status it responded with"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /moxy/{path} [get]
+// @Router /moxy/{path} [post]
+// @Router /moxy/{path} [put]
+// @Router /moxy/{path} [patch]
+// @Router /moxy/{path} [delete]
+func proxyMoxy(ctx *gin.Context) {
+	err := business.ProxyMoxy(ctx, ctx.Param(constants.ProxyPathParam), ctx.Writer, ctx.Request)
+	if err == nil {
+		return
+	}
+	log.Error(ctx).Stack().Err(err).Msg("error proxying moxy")
+	if ctx.Writer.Written() {
+		// the response was cut short on the way, there is nothing more to tell the client
+		return
+	}
+	switch {
+	case errors.Is(err, httpclient.ErrInvalidProxyPath):
+		ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:        constants.RequestValidationError,
+			Description: err.Error(),
+		})
+	case errors.Is(err, httpclient.ErrRequestBodyTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Code:        constants.RequestBodyTooLargeError,
+			Description: err.Error(),
+		})
+	case errors.Is(err, httpclient.ErrResponseBodyTooLarge):
+		ctx.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Code:        constants.UpstreamInvalidResponseError,
+			Description: err.Error(),
+		})
+	case errors.Is(err, httpclient.ErrUnknownRequest), errors.Is(err, httpclient.ErrProxyDisabled):
+		ctx.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:        constants.UnknownUpstreamError,
+			Description: err.Error(),
+		})
+	default:
 		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Code:        constants.ExternalServiceFailureError,
 			Description: err.Error(),
 		})
-		return
 	}
+}
 
-	// now handle success scenario
-	ctx.JSON(http.StatusOK, data)
+// sendUpstreamError responds with a bad gateway for the failed or invalid upstream responses,
+// carrying the status the upstream responded with
+func sendUpstreamError(ctx *gin.Context, err error) {
+	var upstreamErr *processor.UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:        constants.ExternalServiceFailureError,
+			Description: err.Error(),
+		})
+		return
+	}
+	code := constants.UpstreamInvalidResponseError
+	switch {
+	case errors.Is(err, processor.ErrUpstreamClient):
+		code = constants.UpstreamClientError
+	case errors.Is(err, processor.ErrUpstreamServer):
+		code = constants.UpstreamServerError
+	}
+	ctx.JSON(http.StatusBadGateway, models.ErrorResponse{
+		Code:           code,
+		Description:    err.Error(),
+		UpstreamStatus: upstreamErr.StatusCode,
+	})
 }
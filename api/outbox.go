@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"github.com/angel-one/go-utils/log"
+	"github.com/gin-gonic/gin"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/outbox"
+	"net/http"
+	"strconv"
+)
+
+// listOutbox godoc
+// @Summary Lists the counter change events in the outbox
+// @Description Lists the counter change events in the outbox, the latest first
+// @ID listOutbox
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Param status query string false "pending or published, all the events when empty"
+// @Param key query string false "counter key"
+// @Param limit query int false "maximum number of events"
+// @Success 200 {array} outbox.Event
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/outbox [get]
+func listOutbox(ctx *gin.Context) {
+	filter := outbox.Filter{
+		Status: ctx.Query(constants.OutboxStatusParam),
+		Key:    ctx.Query(constants.CounterKey),
+	}
+	if limit := ctx.Query(constants.OutboxLimitParam); limit != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			sendOutboxRequestValidationError(ctx, errors.New("invalid limit provided, has to be a number"))
+			return
+		}
+	}
+	if filter.Status != "" && filter.Status != constants.PendingOutboxStatus &&
+		filter.Status != constants.PublishedOutboxStatus {
+		sendOutboxRequestValidationError(ctx, errors.New("invalid status provided, has to be pending or published"))
+		return
+	}
+
+	events, err := outbox.List(ctx, filter)
+	if err != nil {
+		sendOutboxInternalServerError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// replayOutbox godoc
+// @Summary Replays the counter change events in the outbox
+// @Description Publishes the event with the id, or all the events for the counter key, again
+// @ID replayOutbox
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Param id query int false "event id"
+// @Param key query string false "counter key"
+// @Success 200 {object} models.ReplayOutboxResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/outbox/replay [post]
+func replayOutbox(ctx *gin.Context) {
+	var id int64
+	if value := ctx.Query(constants.OutboxIDParam); value != "" {
+		var err error
+		id, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			sendOutboxRequestValidationError(ctx, errors.New("invalid id provided, has to be a positive number"))
+			return
+		}
+	}
+	key := ctx.Query(constants.CounterKey)
+	if id == 0 && key == "" {
+		sendOutboxRequestValidationError(ctx, errors.New("either the id or the key is required"))
+		return
+	}
+
+	replayed, err := outbox.Replay(ctx, id, key)
+	if err != nil {
+		sendOutboxInternalServerError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.ReplayOutboxResponse{Replayed: replayed})
+}
+
+func sendOutboxRequestValidationError(ctx *gin.Context, err error) {
+	log.Error(ctx).Err(err).Msg("invalid outbox request")
+	ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Code:        constants.RequestValidationError,
+		Description: err.Error(),
+	})
+}
+
+func sendOutboxInternalServerError(ctx *gin.Context, err error) {
+	log.Error(ctx).Stack().Err(err).Msg("unable to work with outbox")
+	ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Code:        constants.DatabaseFailureError,
+		Description: err.Error(),
+	})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	"github.com/sinhashubham95/go-example-project/utils/reload"
+	"net/http"
+)
+
+// reloadConfigs godoc
+// @Summary Reloads the configs
+// @Description Validates the configs and applies their changes, keeping the last good configs when they are invalid
+// @ID reloadConfigs
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Success 200 {object} reload.Result
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/reload [post]
+func reloadConfigs(ctx *gin.Context) {
+	result, err := reload.Reload(ctx)
+	var validationErr *configs.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		ctx.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Code:        constants.InvalidConfigError,
+			Description: err.Error(),
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:        constants.InvalidConfigError,
+			Description: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, result)
+	}
+}
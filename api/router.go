@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
 	"github.com/swaggo/gin-swagger"
 	"github.com/swaggo/gin-swagger/swaggerFiles"
 )
@@ -16,6 +17,7 @@ func GetRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 	router := gin.New()
 	router.Use(middlewares...)
 	router.Use(gin.Recovery())
+	router.Use(traceHeaders)
 
 	// configure swagger
 	router.GET(constants.SwaggerRoute, ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -26,10 +28,27 @@ func GetRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 	// adding api
 	router.POST(constants.FullNameRoute, fullName)
 	router.GET(constants.MoxyRoute, moxy)
-	router.POST(constants.CreateCounterRoute, createCounter)
-	router.PUT(constants.IncrementCounterRoute, incrementCounter)
-	router.POST(constants.DecrementCounterRoute, decrementCounter)
-	router.GET(constants.CurrentCountRoute, currentCount)
+	router.Any(constants.MoxyProxyRoute, proxyMoxy)
+	router.POST(constants.CreateCounterRoute, requireDatabase, createCounter)
+	router.PUT(constants.IncrementCounterRoute, requireDatabase, incrementCounter)
+	router.POST(constants.DecrementCounterRoute, requireDatabase, decrementCounter)
+	router.GET(constants.CurrentCountRoute, requireDatabase, currentCount)
+
+	// adding admin api
+	admin := router.Group(constants.AdminRoute, requireAdmin)
+	admin.GET(constants.OutboxRoute, requireDatabase, listOutbox)
+	admin.POST(constants.ReplayOutboxRoute, requireDatabase, replayOutbox)
+	admin.POST(constants.ForceOpenCircuitRoute, forceOpenCircuit)
+	admin.POST(constants.ResetCircuitRoute, resetCircuit)
+	admin.POST(constants.ReloadConfigsRoute, reloadConfigs)
 
 	return router
 }
+
+// traceHeaders keeps the trace headers of the request on the context, for the http client to propagate upstream
+func traceHeaders(ctx *gin.Context) {
+	if trace := httpclient.GetTraceHeaders(ctx.Request.Header); len(trace) > 0 {
+		ctx.Set(constants.TraceHeadersKey, trace)
+	}
+	ctx.Next()
+}
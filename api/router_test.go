@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/sinhashubham95/go-example-project/api"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,3 +22,36 @@ func TestPing(t *testing.T) {
 	assert.NoError(t, err)
 	testAPI(t, request, http.StatusOK)
 }
+
+func TestAdminWithoutToken(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "/admin/reload", nil)
+	assert.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer token")
+	testAPI(t, request, http.StatusForbidden)
+}
+
+func TestLiveness(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, "/actuator/health/liveness", nil)
+	assert.NoError(t, err)
+	testAPI(t, request, http.StatusOK)
+}
+
+func TestAdminWithWrongToken(t *testing.T) {
+	c := configs.GetConfig()
+	configs.SetConfig(&configs.Config{Application: configs.ApplicationConfig{
+		Admin: configs.AdminConfig{Token: "secret"},
+	}})
+	defer configs.SetConfig(c)
+
+	request, err := http.NewRequest(http.MethodPost, "/admin/reload", nil)
+	assert.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer token")
+	testAPI(t, request, http.StatusUnauthorized)
+}
+
+func TestReadiness(t *testing.T) {
+	// the application is live while the database is unreachable, but not ready
+	request, err := http.NewRequest(http.MethodGet, "/actuator/health/readiness", nil)
+	assert.NoError(t, err)
+	testAPI(t, request, http.StatusServiceUnavailable)
+}
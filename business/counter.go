@@ -6,36 +6,55 @@ import (
 	"errors"
 	"github.com/angel-one/go-utils/log"
 	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/models"
+	"github.com/sinhashubham95/go-example-project/utils/cache"
 	"github.com/sinhashubham95/go-example-project/utils/configs"
 	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/outbox"
+	"hash/fnv"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
+// errors returned by the counter operations
+var (
+	ErrCounterNotFound      = errors.New("counter does not exist")
+	ErrCounterAlreadyExists = errors.New("counter already exists")
+)
+
+// counterGenerations are bumped whenever a counter changes, striped by the key so that they take a fixed space
+// A count read while the counter changed is not cached, as it could be older than the change. This holds for the
+// changes made by this instance, the ones made by the others can leave an older count cached until it expires.
+var counterGenerations [constants.CounterGenerationStripes]uint64
+
 // CreateCounter is used to create a new counter against this key
 func CreateCounter(ctx context.Context, key string) error {
 	ctx, cancel := getCounterContext(ctx)
 	defer cancel()
 
-	db := database.Get()
-
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	// check if counter already exists
-	_, exists := doesCounterExist(ctx, tx, key)
-	if exists {
-		return errors.New("counter already exists")
-	}
-
-	// now that it does not exist, create a new one
-	_, err = tx.ExecContext(ctx, "insert into counter (id, count) values (?, 0)", key)
+	err := database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		// create the counter only if it does not exist already
+		result, err := tx.ExecContext(database.WithQueryName(ctx, "createCounter"),
+			database.Rebind(database.Upsert("counter", []string{"id"}, []string{"id", "count"}, nil)), key, 0)
+		if err != nil {
+			return err
+		}
+		created, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if created == 0 {
+			return ErrCounterAlreadyExists
+		}
+		return outbox.Write(ctx, tx, key, constants.CounterCreatedEvent, models.CounterEvent{Key: key})
+	})
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	invalidateCachedCount(ctx, key)
+	return nil
 }
 
 // IncrementCounter is used to increment the count for the counter if it already exists
@@ -43,26 +62,34 @@ func IncrementCounter(ctx context.Context, key string) error {
 	ctx, cancel := getCounterContext(ctx)
 	defer cancel()
 
-	db := database.Get()
-
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	// check if counter already exists
-	count, exists := doesCounterExist(ctx, tx, key)
-	if !exists {
-		return errors.New("counter does not exist")
-	}
+	err := database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		// increment the counter in place, so that the concurrent increments are not lost
+		result, err := tx.ExecContext(database.WithQueryName(ctx, "incrementCount"),
+			database.Rebind("update counter set count = count + 1 where id = ?"), key)
+		if err != nil {
+			return err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrCounterNotFound
+		}
 
-	// now that counter exists, increment it
-	_, err = tx.ExecContext(ctx, "update counter set count = ? where id = ?", count+1, key)
+		// the row is held by the update, so the count read is the one it wrote
+		count, err := getCount(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, key, constants.CounterIncrementedEvent, models.CounterEvent{Key: key, Count: count})
+	})
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	invalidateCachedCount(ctx, key)
+	return nil
 }
 
 // DecrementCounter is used to decrement the count for the counter if it already exists
@@ -70,74 +97,136 @@ func DecrementCounter(ctx context.Context, key string) error {
 	ctx, cancel := getCounterContext(ctx)
 	defer cancel()
 
-	db := database.Get()
-
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	// check if counter already exists
-	count, exists := doesCounterExist(ctx, tx, key)
-	if !exists {
-		return errors.New("counter does not exist")
-	}
+	err := database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		// decrement the counter in place, so that the concurrent decrements are not lost, never below 0
+		result, err := tx.ExecContext(database.WithQueryName(ctx, "decrementCount"),
+			database.Rebind("update counter set count = count - 1 where id = ? and count > 0"), key)
+		if err != nil {
+			return err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	// now that counter exists, increment it
-	if count > 0 {
-		_, err = tx.ExecContext(ctx, "update counter set count = ? where id = ?", count-1, key)
+		// the row is held by the update, so the count read is the one it wrote, and nothing was updated either
+		// when the counter does not exist or when it is 0 already
+		count, err := getCount(ctx, tx, key)
 		if err != nil {
 			return err
 		}
-	} else {
-		log.Info(ctx).Msgf("count for key %s is 0", key)
+		if updated == 0 {
+			log.Info(ctx).Msgf("count for key %s is 0", key)
+			return nil
+		}
+		return outbox.Write(ctx, tx, key, constants.CounterDecrementedEvent, models.CounterEvent{Key: key, Count: count})
+	})
+	if err != nil {
+		return err
 	}
 
-	return tx.Commit()
+	invalidateCachedCount(ctx, key)
+	return nil
 }
 
 // CurrentCount is used to get the current value of counter if it exists
-func CurrentCount(ctx context.Context, key string) (int, error) {
+// The cached value is used unless skipCache is set, in which case the database is always read.
+func CurrentCount(ctx context.Context, key string, skipCache bool) (int, error) {
 	ctx, cancel := getCounterContext(ctx)
 	defer cancel()
 
-	db := database.Get()
+	if !skipCache {
+		if count, ok := getCachedCount(ctx, key); ok {
+			return count, nil
+		}
+	}
 
-	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	generation := atomic.LoadUint64(getCounterGeneration(key))
+	var count int
+	err := database.InTransaction(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		var err error
+		count, err = getCount(ctx, tx, key)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	// check if counter already exists
-	count, exists := doesCounterExist(ctx, tx, key)
-	if !exists {
-		return 0, errors.New("counter does not exist")
-	}
-
+	setCachedCount(ctx, key, count, generation)
 	return count, nil
 }
 
 func getCounterContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	counterQueryTimeoutInMillis, err := configs.Get().GetInt(constants.CounterConfig,
-		constants.CounterQueryTimeoutInMillisKey)
-	if err != nil {
+	counterQueryTimeoutInMillis := configs.GetConfig().Application.Counter.QueryTimeoutInMillis
+	if counterQueryTimeoutInMillis <= 0 {
 		return ctx, func() {}
 	}
 	return context.WithTimeout(ctx, time.Millisecond*time.Duration(counterQueryTimeoutInMillis))
 }
 
-func doesCounterExist(ctx context.Context, tx *sql.Tx, key string) (int, bool) {
-	row := tx.QueryRowContext(ctx, "select count from counter where id = ?", key)
+func getCount(ctx context.Context, tx *sql.Tx, key string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(database.WithQueryName(ctx, "getCount"),
+		database.Rebind("select count from counter where id = ?"), key).Scan(&count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrCounterNotFound
+	}
+	return count, err
+}
+
+func getCounterCacheKey(key string) string {
+	return "counter:" + key
+}
+
+func getCounterGeneration(key string) *uint64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &counterGenerations[h.Sum32()%uint32(len(counterGenerations))]
+}
 
-	if row == nil || errors.Is(row.Err(), sql.ErrNoRows) {
+func getCachedCount(ctx context.Context, key string) (int, bool) {
+	value, ok, err := cache.Get().Get(ctx, getCounterCacheKey(key))
+	if err != nil {
+		log.Error(ctx).Err(err).Msgf("error reading cached count for key %s", key)
 		return 0, false
 	}
-
-	var count int
-	err := row.Scan(&count)
+	if !ok {
+		return 0, false
+	}
+	count, err := strconv.Atoi(string(value))
 	if err != nil {
+		log.Error(ctx).Err(err).Msgf("invalid cached count for key %s", key)
 		return 0, false
 	}
-
 	return count, true
 }
+
+// setCachedCount is used to cache the count read at the generation, unless the counter changed since
+func setCachedCount(ctx context.Context, key string, count int, generation uint64) {
+	current := getCounterGeneration(key)
+	if atomic.LoadUint64(current) != generation {
+		return
+	}
+	err := cache.Get().Set(ctx, getCounterCacheKey(key), []byte(strconv.Itoa(count)))
+	if err != nil {
+		log.Error(ctx).Err(err).Msgf("error caching count for key %s", key)
+		return
+	}
+	// the counter could have changed and been invalidated while the count was being cached
+	if atomic.LoadUint64(current) != generation {
+		deleteCachedCount(ctx, key)
+	}
+}
+
+// invalidateCachedCount is used once the counter changed, for the counts read before the change to not be cached
+func invalidateCachedCount(ctx context.Context, key string) {
+	atomic.AddUint64(getCounterGeneration(key), 1)
+	deleteCachedCount(ctx, key)
+}
+
+func deleteCachedCount(ctx context.Context, key string) {
+	err := cache.Get().Delete(ctx, getCounterCacheKey(key))
+	if err != nil {
+		log.Error(ctx).Err(err).Msgf("error invalidating cached count for key %s", key)
+	}
+}
@@ -0,0 +1,228 @@
+package business_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/business"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/cache"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/database/databasetest"
+	"github.com/stretchr/testify/assert"
+)
+
+var postgresPlaceholder = regexp.MustCompile(`\$\d+`)
+
+// counterTable is an in-memory stand in for the counter table which understands the statements
+// generated for one dialect and rejects the ones generated for any other
+type counterTable struct {
+	dialect string
+	mu      sync.Mutex
+	counts  map[string]int64
+	// afterRead is called once the count is read, before it is returned
+	afterRead func()
+}
+
+func (d *counterTable) validate(query string) error {
+	if d.dialect == constants.PostgresDialect && strings.Contains(query, "?") {
+		return fmt.Errorf("unexpected ? placeholder in %s", query)
+	}
+	if d.dialect != constants.PostgresDialect && postgresPlaceholder.MatchString(query) {
+		return fmt.Errorf("unexpected $ placeholder in %s", query)
+	}
+	if strings.HasPrefix(query, "insert") {
+		conflict := " on conflict (id) do nothing"
+		if d.dialect == constants.MySQLDialect {
+			conflict = " on duplicate key update id = id"
+		}
+		if !strings.HasSuffix(query, conflict) {
+			return fmt.Errorf("unexpected upsert %s", query)
+		}
+	}
+	return nil
+}
+
+func (d *counterTable) exec(_, query string, args []driver.Value) (driver.Result, error) {
+	query = postgresPlaceholder.ReplaceAllString(query, "?")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "insert into counter (id, count) values (?, ?)"):
+		if _, ok := d.counts[args[0].(string)]; ok {
+			return driver.RowsAffected(0), nil
+		}
+		d.counts[args[0].(string)] = args[1].(int64)
+		return driver.RowsAffected(1), nil
+	case query == "update counter set count = count + 1 where id = ?":
+		if _, ok := d.counts[args[0].(string)]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		d.counts[args[0].(string)]++
+		return driver.RowsAffected(1), nil
+	case query == "update counter set count = count - 1 where id = ? and count > 0":
+		if count, ok := d.counts[args[0].(string)]; !ok || count == 0 {
+			return driver.RowsAffected(0), nil
+		}
+		d.counts[args[0].(string)]--
+		return driver.RowsAffected(1), nil
+	default:
+		return nil, fmt.Errorf("unexpected statement %s", query)
+	}
+}
+
+func (d *counterTable) query(_, query string, args []driver.Value) (driver.Rows, error) {
+	query = postgresPlaceholder.ReplaceAllString(query, "?")
+	d.mu.Lock()
+	if query != "select count from counter where id = ?" {
+		d.mu.Unlock()
+		return nil, fmt.Errorf("unexpected query %s", query)
+	}
+	var values [][]driver.Value
+	if count, ok := d.counts[args[0].(string)]; ok {
+		values = append(values, []driver.Value{count})
+	}
+	afterRead := d.afterRead
+	d.afterRead = nil
+	d.mu.Unlock()
+	if afterRead != nil {
+		afterRead()
+	}
+	return databasetest.NewRows([]string{"count"}, values...), nil
+}
+
+var configsOnce sync.Once
+
+func initCounterDatabase(t *testing.T, dialect string) *counterTable {
+	configsOnce.Do(func() {
+		assert.NoError(t, configs.Init("../resources", "", constants.ApplicationConfig))
+	})
+	driverName := "counter-" + dialect
+	table := &counterTable{dialect: dialect, counts: make(map[string]int64)}
+	databasetest.Register(driverName, databasetest.Handlers{
+		Prepare: table.validate,
+		Exec:    table.exec,
+		Query:   table.query,
+	})
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{
+		Dialect: dialect,
+		Driver:  driverName,
+		Name:    "counter",
+	}))
+	t.Cleanup(func() {
+		assert.NoError(t, database.Close())
+	})
+	return table
+}
+
+func testCounter(t *testing.T, dialect string) {
+	initCounterDatabase(t, dialect)
+	ctx := context.Background()
+
+	assert.NoError(t, business.CreateCounter(ctx, "a"))
+	assert.Equal(t, business.ErrCounterAlreadyExists, business.CreateCounter(ctx, "a"))
+
+	count, err := business.CurrentCount(ctx, "a", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, business.IncrementCounter(ctx, "a"))
+	assert.NoError(t, business.IncrementCounter(ctx, "a"))
+	assert.NoError(t, business.DecrementCounter(ctx, "a"))
+	count, err = business.CurrentCount(ctx, "a", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, business.DecrementCounter(ctx, "a"))
+	assert.NoError(t, business.DecrementCounter(ctx, "a"))
+	count, err = business.CurrentCount(ctx, "a", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.Equal(t, business.ErrCounterNotFound, business.IncrementCounter(ctx, "b"))
+	assert.Equal(t, business.ErrCounterNotFound, business.DecrementCounter(ctx, "b"))
+	_, err = business.CurrentCount(ctx, "b", false)
+	assert.Equal(t, business.ErrCounterNotFound, err)
+}
+
+func TestCounterMySQL(t *testing.T) {
+	testCounter(t, constants.MySQLDialect)
+}
+
+func TestCounterPostgres(t *testing.T) {
+	testCounter(t, constants.PostgresDialect)
+}
+
+func TestCounterSQLite(t *testing.T) {
+	testCounter(t, constants.SQLiteDialect)
+}
+
+func TestConcurrentIncrements(t *testing.T) {
+	initCounterDatabase(t, constants.MySQLDialect)
+	ctx := context.Background()
+	assert.NoError(t, business.CreateCounter(ctx, "concurrent"))
+
+	// every increment counts, however they interleave
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, business.IncrementCounter(ctx, "concurrent"))
+		}()
+	}
+	wg.Wait()
+	count, err := business.CurrentCount(ctx, "concurrent", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, count)
+
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, business.DecrementCounter(ctx, "concurrent"))
+		}()
+	}
+	wg.Wait()
+	count, err = business.CurrentCount(ctx, "concurrent", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestCachedCountInvalidation(t *testing.T) {
+	d := initCounterDatabase(t, constants.MySQLDialect)
+	ctx := context.Background()
+	assert.NoError(t, cache.InitCache(cache.Config{Backend: constants.LRUCacheBackend, Size: 10}))
+	t.Cleanup(func() {
+		assert.NoError(t, cache.InitCache(cache.Config{Backend: constants.NoCacheBackend}))
+	})
+	assert.NoError(t, business.CreateCounter(ctx, "cached"))
+
+	// the count is cached once read, and invalidated by the change
+	count, err := business.CurrentCount(ctx, "cached", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.NoError(t, business.IncrementCounter(ctx, "cached"))
+	count, err = business.CurrentCount(ctx, "cached", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// a count read before a change committing while it is read is not cached over the change
+	d.mu.Lock()
+	d.afterRead = func() {
+		assert.NoError(t, business.IncrementCounter(ctx, "cached"))
+	}
+	d.mu.Unlock()
+	count, err = business.CurrentCount(ctx, "cached", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = business.CurrentCount(ctx, "cached", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
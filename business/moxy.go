@@ -4,18 +4,15 @@ import (
 	"context"
 	"github.com/sinhashubham95/go-example-project/external"
 	"github.com/sinhashubham95/go-example-project/models"
+	"net/http"
 )
 
 // GetMoxy is used to get the moxy response
 func GetMoxy(ctx context.Context) (models.MoxyResponse, error) {
-	response := models.MoxyResponse{}
-
-	data, err := external.GetMoxy(ctx)
-	if err != nil {
-		return response, err
-	}
-
-	response.Data = data
+	return external.GetMoxy(ctx)
+}
 
-	return response, nil
+// ProxyMoxy is used to proxy the request to moxy at the path, streaming its response to the writer
+func ProxyMoxy(ctx context.Context, path string, writer http.ResponseWriter, request *http.Request) error {
+	return external.ProxyMoxy(ctx, path, writer, request)
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+	"os"
+)
+
+// config prints the effective configs, with the overlays of the environment merged into them and the keys overridden
+// by the environment variables, the secrets redacted, or validates them, exiting with 1 when they are invalid
+func main() {
+	env := flag.String(constants.EnvKey, constants.EnvDefaultValue, constants.EnvUsage)
+	baseConfigPath := flag.String(constants.BaseConfigPathKey, constants.BaseConfigPathDefaultValue,
+		constants.BaseConfigPathUsage)
+	names := flag.StringSlice(constants.ConfigNamesKey, []string{constants.LoggerConfig,
+		constants.ApplicationConfig, constants.DatabaseConfig}, constants.ConfigNamesUsage)
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case constants.PrintConfigCommand:
+		printConfigs(*baseConfigPath, *env, *names)
+	case constants.ValidateConfigCommand:
+		validateConfigs(*baseConfigPath, *env)
+	default:
+		fmt.Fprintln(os.Stderr, constants.ConfigCommandUsage)
+		os.Exit(2)
+	}
+}
+
+func printConfigs(baseConfigPath, env string, names []string) {
+	if err := configs.Init(baseConfigPath, env, names...); err != nil {
+		fmt.Fprintln(os.Stderr, "error initialising configs:", err)
+		os.Exit(1)
+	}
+	for _, name := range names {
+		config, err := configs.GetEffective(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error getting the effective config:", err)
+			os.Exit(1)
+		}
+		data, err := yaml.Marshal(configs.Redact(config))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error encoding the effective config:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("# %s\n%s\n", name, data)
+	}
+}
+
+func validateConfigs(baseConfigPath, env string) {
+	err := configs.Init(baseConfigPath, env, constants.LoggerConfig, constants.ApplicationConfig,
+		constants.DatabaseConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error initialising configs:", err)
+		os.Exit(1)
+	}
+	_, warnings, err := configs.Load()
+	for _, key := range warnings {
+		fmt.Fprintln(os.Stderr, "unknown config key:", key)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("configs are valid")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/utils/secrets"
+	"os"
+	"strings"
+)
+
+// encrypt-secret reads a secret from the standard input and prints the enc: reference for it
+func main() {
+	keyFile := flag.String("key-file", "", "file holding the base64 encoded AES key")
+	flag.Parse()
+
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		fmt.Fprintln(os.Stderr, "error reading secret:", err)
+		os.Exit(1)
+	}
+	reference, err := secrets.Encrypt(*keyFile, strings.TrimRight(value, "\r\n"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error encrypting secret:", err)
+		os.Exit(1)
+	}
+	fmt.Println(reference)
+}
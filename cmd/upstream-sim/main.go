@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/upstreamsim"
+	flag "github.com/spf13/pflag"
+	"net/http"
+)
+
+// upstream-sim serves the fake endpoints of a yaml spec, for the upstream urls to be pointed at locally
+func main() {
+	ctx := context.Background()
+	spec := flag.String(constants.SimulatorSpecKey, constants.SimulatorSpecDefaultValue, constants.SimulatorSpecUsage)
+	port := flag.Int(constants.PortKey, constants.SimulatorPortDefaultValue, constants.SimulatorPortUsage)
+	flag.Parse()
+
+	s, err := upstreamsim.Load(*spec)
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("error loading spec")
+	}
+	simulator, err := upstreamsim.New(s)
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("error creating simulator")
+	}
+	log.Info(ctx).Msgf("serving %d simulated endpoints from %s on port %d", len(s.Endpoints), *spec, *port)
+	if err = http.ListenAndServe(fmt.Sprintf(":%d", *port), simulator); err != nil {
+		log.Fatal(ctx).Err(err).Msg("error serving simulated endpoints")
+	}
+}
@@ -5,20 +5,62 @@ const (
 	LoggerConfig      = "logger"
 	ApplicationConfig = "application"
 	DatabaseConfig    = "database"
-	CounterConfig     = "counter"
+	ConfigEnvPrefix   = "APP_"
 )
 
-// config keys
+// config value sources
 const (
-	LogLevelConfigKey                         = "level"
-	DatabaseServerConfigKey                   = "server"
-	DatabasePortConfigKey                     = "port"
-	DatabaseNameConfigKey                     = "name"
-	DatabaseUsernameConfigKey                 = "username"
-	DatabasePasswordConfigKey                 = "password"
-	DatabaseMaxOpenConnectionsKey             = "maxOpenConnections"
-	DatabaseMaxIdleConnectionsKey             = "maxIdleConnections"
-	DatabaseConnectionMaxLifetimeInSecondsKey = "connectionMaxLifetimeInSeconds"
-	DatabaseConnectionMaxIdleTimeInSecondsKey = "connectionMaxIdleTimeInSeconds"
-	CounterQueryTimeoutInMillisKey            = "queryTimeoutInMillis"
+	FileConfigSource    = "file"
+	OverlayConfigSource = "overlay"
+	EnvConfigSource     = "env"
+	DefaultConfigSource = "default"
+)
+
+// http request configuration keys, every entry under http is a request named after its key
+const (
+	HTTPRequestsConfigKey                          = "http"
+	HTTPMethodConfigKey                            = "method"
+	HTTPURLConfigKey                               = "url"
+	HTTPHystrixConfigKey                           = "hystrixconfig"
+	HTTPCacheConfigKey                             = "cache"
+	HTTPCacheEnabledConfigKey                      = "enabled"
+	HTTPCacheSizeConfigKey                         = "size"
+	HTTPCacheTTLInMillisConfigKey                  = "ttlInMillis"
+	HTTPCacheStaleWhileRevalidateInMillisConfigKey = "staleWhileRevalidateInMillis"
+	HTTPCacheStaleIfErrorInMillisConfigKey         = "staleIfErrorInMillis"
+	HTTPFallbackConfigKey                          = "fallback"
+	HTTPFallbackStrategyConfigKey                  = "strategy"
+	HTTPFallbackStatusCodeConfigKey                = "statusCode"
+	HTTPFallbackContentTypeConfigKey               = "contentType"
+	HTTPFallbackBodyConfigKey                      = "body"
+	HTTPFallbackURLConfigKey                       = "url"
+	HTTPFallbackSizeConfigKey                      = "size"
+	HTTPAttemptsConfigKey                          = "attempts"
+	HTTPAttemptTimeoutInMillisConfigKey            = "timeoutInMillis"
+	HTTPAttemptsDeadlineInMillisConfigKey          = "deadlineInMillis"
+	HTTPRetriesConfigKey                           = "retries"
+	HTTPRetryBackoffInMillisConfigKey              = "retryBackoffInMillis"
+	HTTPHedgeAfterPercentileConfigKey              = "hedgeAfterPercentile"
+	HTTPHedgeDelayInMillisConfigKey                = "hedgeDelayInMillis"
+	HTTPMaxHedgesConfigKey                         = "maxHedges"
+	HTTPRetryBudgetPercentConfigKey                = "retryBudgetPercent"
+	HTTPMinRetriesPerSecondConfigKey               = "minRetriesPerSecond"
+	HTTPAuthConfigKey                              = "auth"
+	HTTPAuthTypeConfigKey                          = "type"
+	HTTPAuthTokenConfigKey                         = "token"
+	HTTPAuthHeaderConfigKey                        = "header"
+	HTTPAuthKeyConfigKey                           = "key"
+	HTTPAuthTokenURLConfigKey                      = "tokenUrl"
+	HTTPAuthClientIDConfigKey                      = "clientId"
+	HTTPAuthClientSecretConfigKey                  = "clientSecret"
+	HTTPAuthScopesConfigKey                        = "scopes"
+	HTTPAuthTimeoutInMillisConfigKey               = "timeoutInMillis"
+	HTTPAuthKeyIDConfigKey                         = "keyId"
+	HTTPAuthSecretConfigKey                        = "secret"
+	HTTPTimeoutInMillisConfigKey                   = "timeoutinmillis"
+	HTTPProxyConfigKey                             = "proxy"
+	HTTPProxyEnabledConfigKey                      = "enabled"
+	HTTPProxyMaxRequestBodySizeConfigKey           = "maxRequestBodySize"
+	HTTPProxyMaxResponseBodySizeConfigKey          = "maxResponseBodySize"
+	HTTPProxyHeadersConfigKey                      = "headers"
 )
@@ -1,8 +1,198 @@
 package constants
 
+import "time"
+
 // common constants
 const (
 	ApplicationName = "go-example-project"
 	MySQLDriverName = "mysql"
 	CounterKey      = "key"
+	TraceHeadersKey = "traceHeaders"
+)
+
+// CounterGenerationStripes is the number of generations the changes of the counters are tracked with
+const CounterGenerationStripes = 1024
+
+// database dialects and the drivers used for them by default
+const (
+	MySQLDialect       = "mysql"
+	PostgresDialect    = "postgres"
+	SQLiteDialect      = "sqlite"
+	PostgresDriverName = "postgres"
+	SQLiteDriverName   = "sqlite3"
+)
+
+// database TLS modes
+const (
+	DisabledTLSMode    = "disabled"
+	PreferredTLSMode   = "preferred"
+	SkipVerifyTLSMode  = "skipVerify"
+	VerifyTLSMode      = "verify"
+	MySQLTLSConfigName = "go-example-project"
+)
+
+// DefaultDatabaseHealthCheckInterval is how often the primary database and its replicas are checked when no interval
+// is configured
+const DefaultDatabaseHealthCheckInterval = 5 * time.Second
+
+// read replica routing strategies
+const (
+	RoundRobinReplicaStrategy   = "roundRobin"
+	LeastLatencyReplicaStrategy = "leastLatency"
+)
+
+// cache backends
+const (
+	NoCacheBackend    = "none"
+	LRUCacheBackend   = "lru"
+	RedisCacheBackend = "redis"
+)
+
+// database query instrumentation
+const (
+	PrimaryDatabase  = "primary"
+	UnknownQueryName = "unknown"
+)
+
+// secret reference prefixes
+const (
+	EnvSecretPrefix       = "env:"
+	FileSecretPrefix      = "file:"
+	EncryptedSecretPrefix = "enc:"
+)
+
+// RedactedValue replaces the secrets in the logs
+const RedactedValue = "[REDACTED]"
+
+// health statuses
+const (
+	UpStatus       = "UP"
+	DownStatus     = "DOWN"
+	DegradedStatus = "DEGRADED"
+)
+
+// outbox sinks, statuses and limits
+const (
+	HTTPOutboxSink            = "http"
+	FileOutboxSink            = "file"
+	OutboxRequestName         = "outbox"
+	PendingOutboxStatus       = "pending"
+	PublishedOutboxStatus     = "published"
+	DefaultOutboxBatchSize    = 100
+	MaxOutboxListLimit        = 500
+	MaxOutboxBackoffDoublings = 32
+	// OutboxClaimLease is how long the events claimed by a relay are kept from the other instances,
+	// after which the events of a relay which died while publishing are claimed again
+	OutboxClaimLease = time.Minute
+)
+
+// counter change events written to the outbox
+const (
+	CounterCreatedEvent     = "counter.created"
+	CounterIncrementedEvent = "counter.incremented"
+	CounterDecrementedEvent = "counter.decremented"
+)
+
+// outbox admin query params
+const (
+	OutboxIDParam     = "id"
+	OutboxStatusParam = "status"
+	OutboxLimitParam  = "limit"
+)
+
+// circuit admin and proxy path params
+const (
+	CircuitNameParam = "name"
+	ProxyPathParam   = "path"
+)
+
+// upstream response limits
+const (
+	MaxUpstreamBodySize        = 1 << 20
+	MaxUpstreamErrorReasonSize = 1 << 10
+)
+
+// upstream proxy limits
+const (
+	DefaultMaxProxyRequestBodySize  = 1 << 20
+	DefaultMaxProxyResponseBodySize = 10 << 20
+	ProxyBufferSize                 = 32 << 10
+)
+
+// http response cache statuses, sent back in the cache status header
+const (
+	HitCacheStatus         = "HIT"
+	MissCacheStatus        = "MISS"
+	StaleCacheStatus       = "STALE"
+	RevalidatedCacheStatus = "REVALIDATED"
+)
+
+// http response cache background revalidations
+const (
+	CacheRevalidationTimeout = 30 * time.Second
+)
+
+// http circuit states and events
+const (
+	NoCircuitState         = "NONE"
+	ClosedCircuitState     = "CLOSED"
+	OpenCircuitState       = "OPEN"
+	ForcedOpenCircuitState = "FORCED_OPEN"
+	SuccessCircuitEvent    = "success"
+)
+
+// http cache control directives
+const (
+	MaxAgeDirective               = "max-age"
+	SharedMaxAgeDirective         = "s-maxage"
+	NoStoreDirective              = "no-store"
+	NoCacheDirective              = "no-cache"
+	PrivateDirective              = "private"
+	MustRevalidateDirective       = "must-revalidate"
+	ProxyRevalidateDirective      = "proxy-revalidate"
+	StaleWhileRevalidateDirective = "stale-while-revalidate"
+	StaleIfErrorDirective         = "stale-if-error"
+	DefaultHTTPCacheSize          = 1000
+)
+
+// http fallback strategies, sent back in the fallback header
+const (
+	CacheFallbackStrategy  = "cache"
+	StaticFallbackStrategy = "static"
+	URLFallbackStrategy    = "url"
+	FallbackRequestSuffix  = "-fallback"
+)
+
+// http fixtures modes and the parts of the requests matched when replaying
+const (
+	RecordFixturesMode   = "record"
+	ReplayFixturesMode   = "replay"
+	AutoFixturesMode     = "auto"
+	FixturesModeEnv      = "HTTP_FIXTURES_MODE"
+	FixtureFileExtension = ".json"
+	MethodFixtureMatch   = "method"
+	PathFixtureMatch     = "path"
+	QueryFixtureMatch    = "query"
+	BodyFixtureMatch     = "body"
+)
+
+// upstream simulator latency distributions
+const (
+	FixedLatencyDistribution   = "fixed"
+	UniformLatencyDistribution = "uniform"
+	NormalLatencyDistribution  = "normal"
+)
+
+// upstream auth types and the oauth2 client credentials grant
+const (
+	BearerAuthType             = "bearer"
+	APIKeyAuthType             = "apikey"
+	OAuth2AuthType             = "oauth2"
+	HMACAuthType               = "hmac"
+	GrantTypeParam             = "grant_type"
+	ScopeParam                 = "scope"
+	ClientCredentialsGrantType = "client_credentials"
+	DefaultTokenTimeout        = 5 * time.Second
+	DefaultTokenLifetime       = 5 * time.Minute
+	TokenRefreshSkew           = 30 * time.Second
 )
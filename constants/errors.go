@@ -6,5 +6,20 @@ const (
 	RequestBodyValidationError  = "request body validation error"
 	ExternalServiceFailureError = "external service failure error"
 	DatabaseFailureError        = "database failure error"
+	DatabaseUnavailableError    = "database unavailable error"
 	RequestValidationError      = "request validation error"
+	RequestBodyTooLargeError    = "request body too large error"
+	InvalidConfigError          = "invalid config error"
+	UnauthorizedError           = "unauthorized error"
+	AdminDisabledError          = "admin disabled error"
+	CounterNotFoundError        = "counter not found error"
+	CounterAlreadyExistsError   = "counter already exists error"
+)
+
+// Upstream error codes
+const (
+	UpstreamClientError          = "upstream client error"
+	UpstreamServerError          = "upstream server error"
+	UpstreamInvalidResponseError = "upstream invalid response error"
+	UnknownUpstreamError         = "unknown upstream error"
 )
@@ -4,7 +4,7 @@ package constants
 const (
 	EnvKey                     = "env"
 	EnvDefaultValue            = ""
-	EnvUsage                   = "application.yml runtime environment"
+	EnvUsage                   = "runtime environment, the overlays of the configs for it are merged into them"
 	PortKey                    = "port"
 	PortDefaultValue           = 8080
 	PortUsage                  = "application.yml port"
@@ -12,3 +12,22 @@ const (
 	BaseConfigPathDefaultValue = "."
 	BaseConfigPathUsage        = "path to folder that stores your configurations"
 )
+
+// upstream simulator flag constants
+const (
+	SimulatorSpecKey          = "spec"
+	SimulatorSpecDefaultValue = "resources/upstream-sim.yml"
+	SimulatorSpecUsage        = "path to the yaml spec of the simulated endpoints"
+	SimulatorPortDefaultValue = 9090
+	SimulatorPortUsage        = "port the simulated endpoints are served on"
+)
+
+// config command flag constants
+const (
+	ConfigNamesKey        = "config"
+	ConfigNamesUsage      = "names of the configs printed, all of them by default"
+	PrintConfigCommand    = "print"
+	ValidateConfigCommand = "validate"
+	ConfigCommandUsage    = "usage: config print [--base-config-path path] [--env env] [--config name]...\n" +
+		"       config validate [--base-config-path path] [--env env]"
+)
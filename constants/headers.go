@@ -0,0 +1,76 @@
+package constants
+
+// Header constants
+const (
+	CacheControlHeader   = "Cache-Control"
+	NoCacheCacheControl  = "no-cache"
+	ReadYourWritesHeader = "X-Read-Your-Writes"
+)
+
+// outbox webhook header constants
+const (
+	ContentTypeHeader = "Content-Type"
+	JSONContentType   = "application/json"
+	EventIDHeader     = "X-Event-Id"
+)
+
+// http response cache header constants
+const (
+	ETagHeader            = "ETag"
+	LastModifiedHeader    = "Last-Modified"
+	IfNoneMatchHeader     = "If-None-Match"
+	IfModifiedSinceHeader = "If-Modified-Since"
+	ExpiresHeader         = "Expires"
+	VaryHeader            = "Vary"
+	CacheStatusHeader     = "X-Cache"
+	FallbackHeader        = "X-Fallback"
+)
+
+// trace header constants, propagated to the upstream requests
+const (
+	TraceParentHeader    = "traceparent"
+	TraceStateHeader     = "tracestate"
+	B3Header             = "b3"
+	B3TraceIDHeader      = "X-B3-TraceId"
+	B3SpanIDHeader       = "X-B3-SpanId"
+	B3ParentSpanIDHeader = "X-B3-ParentSpanId"
+	B3SampledHeader      = "X-B3-Sampled"
+	B3FlagsHeader        = "X-B3-Flags"
+)
+
+// upstream auth header constants
+const (
+	AuthorizationHeader = "Authorization"
+	APIKeyHeader        = "X-Api-Key"
+	TimestampHeader     = "X-Timestamp"
+	ContentSHA256Header = "X-Content-Sha256"
+	FormContentType     = "application/x-www-form-urlencoded"
+	BearerTokenType     = "Bearer"
+	HMACAuthScheme      = "HMAC-SHA256"
+)
+
+// admin api header constants
+const (
+	WWWAuthenticateHeader = "WWW-Authenticate"
+)
+
+// proxy header constants, the hop-by-hop ones never being forwarded
+const (
+	AcceptHeader             = "Accept"
+	AcceptEncodingHeader     = "Accept-Encoding"
+	AcceptLanguageHeader     = "Accept-Language"
+	ContentEncodingHeader    = "Content-Encoding"
+	UserAgentHeader          = "User-Agent"
+	ForwardedForHeader       = "X-Forwarded-For"
+	ForwardedHostHeader      = "X-Forwarded-Host"
+	ForwardedProtoHeader     = "X-Forwarded-Proto"
+	ConnectionHeader         = "Connection"
+	KeepAliveHeader          = "Keep-Alive"
+	ProxyAuthenticateHeader  = "Proxy-Authenticate"
+	ProxyAuthorizationHeader = "Proxy-Authorization"
+	ProxyConnectionHeader    = "Proxy-Connection"
+	TEHeader                 = "Te"
+	TrailerHeader            = "Trailer"
+	TransferEncodingHeader   = "Transfer-Encoding"
+	UpgradeHeader            = "Upgrade"
+)
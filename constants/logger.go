@@ -2,14 +2,34 @@ package constants
 
 // Log keys
 const (
-	StatusCodeKey = "statusCode"
-	LatencyKey    = "latency"
-	ClientIPKey   = "clientIP"
-	MethodKey     = "method"
-	PathKey       = "path"
-	ErrorKey      = "error"
+	StatusCodeKey  = "statusCode"
+	LatencyKey     = "latency"
+	ClientIPKey    = "clientIP"
+	MethodKey      = "method"
+	PathKey        = "path"
+	ErrorKey       = "error"
+	QueryNameKey   = "queryName"
+	QueryKey       = "query"
+	FilesKey       = "files"
+	RequestNameKey = "requestName"
+	URLKey         = "url"
+	AttemptsKey    = "attempts"
+
+	OutboxEventIDKey  = "eventID"
+	OutboxEventKeyKey = "eventKey"
 
 	HTTPConfigKey     = "httpConfig"
 	DatabaseConfigKey = "databaseConfig"
+	CacheConfigKey    = "cacheConfig"
+	SecretsConfigKey  = "secretsConfig"
+	OutboxConfigKey   = "outboxConfig"
 	LogLevelKey       = "logLevel"
+
+	ConfigNameKey = "config"
+	ConfigKeyKey  = "key"
+	EnvVarKey     = "env"
+	ValueKey      = "value"
+
+	AppliedKey         = "applied"
+	RestartRequiredKey = "restartRequired"
 )
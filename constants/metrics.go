@@ -0,0 +1,35 @@
+package constants
+
+// Metric names
+const (
+	CacheHitsMetric    = "cache.hits"
+	CacheMissesMetric  = "cache.misses"
+	CacheErrorsMetric  = "cache.errors"
+	CacheLatencyMetric = "cache.latency"
+
+	DatabaseReplicasMetric = "database.replicas"
+	DatabasePoolMetric     = "database.pool"
+
+	// the query metrics are formatted with the name of the query
+	DatabaseQueryLatencyMetric = "database.query.%s.latency"
+	DatabaseQueryErrorsMetric  = "database.query.%s.errors"
+)
+
+// outbox metric names
+const (
+	OutboxPublishedMetric = "outbox.published"
+	OutboxFailuresMetric  = "outbox.failures"
+)
+
+// http client metric names, formatted with the name of the request and the cache status or fallback strategy
+const (
+	HTTPCacheMetric    = "http.%s.cache.%s"
+	HTTPFallbackMetric = "http.%s.fallback.%s"
+	HTTPLatencyMetric  = "http.%s.latency"
+	HTTPErrorsMetric   = "http.%s.errors"
+	HTTPAttemptsMetric = "http.%s.attempts"
+	HTTPRetriesMetric  = "http.%s.retries"
+	HTTPHedgesMetric   = "http.%s.hedges"
+
+	HTTPRetryBudgetExhaustedMetric = "http.%s.retries.exhausted"
+)
@@ -6,8 +6,29 @@ const (
 	ActuatorRoute         = "/actuator/*any"
 	FullNameRoute         = "/fullName"
 	MoxyRoute             = "/moxy"
+	MoxyProxyRoute        = "/moxy/*path"
 	CreateCounterRoute    = "/counter/create"
 	IncrementCounterRoute = "/counter/increment"
 	DecrementCounterRoute = "/counter/decrement"
 	CurrentCountRoute     = "/counter/current"
 )
+
+// Admin route constants, relative to the admin route
+const (
+	AdminRoute            = "/admin"
+	OutboxRoute           = "/outbox"
+	ReplayOutboxRoute     = "/outbox/replay"
+	ForceOpenCircuitRoute = "/circuits/:name/open"
+	ResetCircuitRoute     = "/circuits/:name/reset"
+	ReloadConfigsRoute    = "/reload"
+)
+
+// Actuator endpoint constants
+const (
+	StatsActuatorEndpoint       = "/stats"
+	HealthActuatorEndpoint      = "/health"
+	LivenessActuatorEndpoint    = "/health/liveness"
+	ReadinessActuatorEndpoint   = "/health/readiness"
+	CircuitsActuatorEndpoint    = "/circuits"
+	ConfigPropsActuatorEndpoint = "/configprops"
+)
@@ -3,14 +3,21 @@ package external
 import (
 	"context"
 	"github.com/sinhashubham95/go-example-project/external/processor"
+	"github.com/sinhashubham95/go-example-project/models"
 	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"net/http"
 )
 
 // GetMoxy is used to get the response from the moxy service
-func GetMoxy(ctx context.Context) (string, error) {
+func GetMoxy(ctx context.Context) (models.MoxyResponse, error) {
 	response, err := httpclient.Get().Request(httpclient.NewRequest("moxy").SetContext(ctx))
 	if err != nil {
-		return "", err
+		return models.MoxyResponse{}, err
 	}
 	return processor.ProcessMoxyResponse(response)
 }
+
+// ProxyMoxy is used to proxy the request to the moxy service at the path, streaming its response to the writer
+func ProxyMoxy(ctx context.Context, path string, writer http.ResponseWriter, request *http.Request) error {
+	return httpclient.Proxy(ctx, "moxy", path, writer, request)
+}
@@ -0,0 +1,36 @@
+package external_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/external"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// the exchanges with moxy are replayed from the fixtures, recorded again with HTTP_FIXTURES_MODE=record
+func TestGetMoxy(t *testing.T) {
+	fixtures := httpclient.NewFixtures(httpclient.FixturesConfig{
+		Directory: "testdata/fixtures",
+		Mode:      constants.ReplayFixturesMode,
+		Strict:    true,
+	})
+	defer func() {
+		assert.NoError(t, fixtures.Close())
+	}()
+	httpclient.Use(fixtures.Middleware)
+	httpclient.Init(httpclient.NewRequestConfig("moxy", map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             "https://mocker-proxy.herokuapp.com",
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+	}))
+
+	moxy, err := external.GetMoxy(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello from mocker proxy", moxy.Data)
+	assert.Empty(t, moxy.Fallback)
+}
@@ -1,15 +1,16 @@
 package processor
 
 import (
-	"errors"
-	"github.com/angel-one/go-utils"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/models"
 	"net/http"
 )
 
 // ProcessMoxyResponse is used to process moxy response
-func ProcessMoxyResponse(response *http.Response) (string, error) {
-	if response.Body == nil {
-		return "", errors.New("no response exists")
+func ProcessMoxyResponse(response *http.Response) (models.MoxyResponse, error) {
+	var message models.MoxyMessage
+	if err := decodeJSON(response, &message); err != nil {
+		return models.MoxyResponse{}, err
 	}
-	return utils.GetDataAsString(response.Body)
+	return models.MoxyResponse{Data: message.Message, Fallback: response.Header.Get(constants.FallbackHeader)}, nil
 }
@@ -0,0 +1,66 @@
+package processor_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/external/processor"
+	"github.com/stretchr/testify/assert"
+)
+
+func newResponse(statusCode int, contentType, body string) *http.Response {
+	response := &http.Response{StatusCode: statusCode, Header: make(http.Header),
+		Body: ioutil.NopCloser(strings.NewReader(body))}
+	response.Header.Set("Content-Type", contentType)
+	return response
+}
+
+func assertUpstreamError(t *testing.T, err error, kind error, statusCode int) {
+	var upstreamErr *processor.UpstreamError
+	assert.True(t, errors.As(err, &upstreamErr))
+	assert.True(t, errors.Is(err, kind))
+	assert.Equal(t, statusCode, upstreamErr.StatusCode)
+}
+
+func TestProcessMoxyResponse(t *testing.T) {
+	moxy, err := processor.ProcessMoxyResponse(newResponse(http.StatusOK, "application/json; charset=utf-8",
+		`{"message": "hello", "extra": true}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", moxy.Data)
+}
+
+func TestProcessMoxyFallbackResponse(t *testing.T) {
+	response := newResponse(http.StatusOK, "application/json", `{"message": "hello"}`)
+	response.Header.Set("X-Fallback", "cache")
+	moxy, err := processor.ProcessMoxyResponse(response)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", moxy.Data)
+	assert.Equal(t, "cache", moxy.Fallback)
+}
+
+func TestProcessMoxyFailedResponse(t *testing.T) {
+	_, err := processor.ProcessMoxyResponse(newResponse(http.StatusNotFound, "text/plain", "no such mock"))
+	assertUpstreamError(t, err, processor.ErrUpstreamClient, http.StatusNotFound)
+	assert.Contains(t, err.Error(), "no such mock")
+
+	_, err = processor.ProcessMoxyResponse(newResponse(http.StatusServiceUnavailable, "application/json", "{}"))
+	assertUpstreamError(t, err, processor.ErrUpstreamServer, http.StatusServiceUnavailable)
+}
+
+func TestProcessMoxyInvalidResponse(t *testing.T) {
+	_, err := processor.ProcessMoxyResponse(newResponse(http.StatusOK, "text/html", "<html></html>"))
+	assertUpstreamError(t, err, processor.ErrInvalidResponse, http.StatusOK)
+
+	_, err = processor.ProcessMoxyResponse(newResponse(http.StatusOK, "application/json", `{"message": 1}`))
+	assertUpstreamError(t, err, processor.ErrInvalidResponse, http.StatusOK)
+
+	_, err = processor.ProcessMoxyResponse(newResponse(http.StatusOK, "application/json", `{}`))
+	assertUpstreamError(t, err, processor.ErrInvalidResponse, http.StatusOK)
+	assert.Contains(t, err.Error(), "message cannot be empty")
+
+	_, err = processor.ProcessMoxyResponse(&http.Response{StatusCode: http.StatusOK})
+	assertUpstreamError(t, err, processor.ErrInvalidResponse, http.StatusOK)
+}
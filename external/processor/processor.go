@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// the kinds of failures for the responses from the upstream services
+var (
+	ErrUpstreamClient  = errors.New("upstream rejected the request")
+	ErrUpstreamServer  = errors.New("upstream failed to serve the request")
+	ErrInvalidResponse = errors.New("upstream responded with an invalid body")
+)
+
+// UpstreamError is a failed or invalid response from an upstream service along with its status
+type UpstreamError struct {
+	// Kind is one of ErrUpstreamClient, ErrUpstreamServer or ErrInvalidResponse
+	Kind       error
+	StatusCode int
+	Reason     string
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("%s, status %d: %s", e.Kind, e.StatusCode, e.Reason)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Kind
+}
+
+// validatable is a model which can check itself once decoded
+type validatable interface {
+	Validate() error
+}
+
+// decodeJSON is used to check the status and the content type of the response,
+// and to decode its body into the model and validate it
+func decodeJSON(response *http.Response, model validatable) error {
+	if response.Body == nil {
+		return &UpstreamError{Kind: ErrInvalidResponse, StatusCode: response.StatusCode, Reason: "no response exists"}
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body := io.LimitReader(response.Body, constants.MaxUpstreamBodySize)
+
+	if err := checkStatus(response.StatusCode, body); err != nil {
+		return err
+	}
+	if !isJSON(response.Header.Get(constants.ContentTypeHeader)) {
+		return &UpstreamError{Kind: ErrInvalidResponse, StatusCode: response.StatusCode,
+			Reason: fmt.Sprintf("unexpected content type %s", response.Header.Get(constants.ContentTypeHeader))}
+	}
+	if err := json.NewDecoder(body).Decode(model); err != nil {
+		return &UpstreamError{Kind: ErrInvalidResponse, StatusCode: response.StatusCode, Reason: err.Error()}
+	}
+	if err := model.Validate(); err != nil {
+		return &UpstreamError{Kind: ErrInvalidResponse, StatusCode: response.StatusCode, Reason: err.Error()}
+	}
+	return nil
+}
+
+// checkStatus keeps the beginning of the body of the failed responses as the reason
+func checkStatus(statusCode int, body io.Reader) error {
+	var kind error
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		kind = ErrUpstreamServer
+	case statusCode >= http.StatusBadRequest:
+		kind = ErrUpstreamClient
+	case statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices:
+		kind = ErrInvalidResponse
+	default:
+		return nil
+	}
+	reason, _ := ioutil.ReadAll(io.LimitReader(body, constants.MaxUpstreamErrorReasonSize))
+	return &UpstreamError{Kind: kind, StatusCode: statusCode, Reason: strings.TrimSpace(string(reason))}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == constants.JSONContentType || strings.HasSuffix(mediaType, "+json")
+}
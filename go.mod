@@ -3,15 +3,19 @@ module github.com/sinhashubham95/go-example-project
 go 1.16
 
 require (
+	github.com/afex/hystrix-go v0.0.0-20180502004556-fa1af6a1f4f5
 	github.com/angel-one/go-config-client v0.2.0
 	github.com/angel-one/go-http-client v0.3.2
 	github.com/angel-one/go-utils v0.1.0
 	github.com/gin-gonic/gin v1.7.4
 	github.com/go-sql-driver/mysql v1.6.0
+	github.com/lib/pq v1.10.3
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/sinhashubham95/go-actuator v1.4.0
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
 	github.com/swaggo/gin-swagger v1.3.2
 	github.com/swaggo/swag v1.7.3
 	golang.org/x/net v0.0.0-20211013171255-e13a2654a71e // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -7,13 +7,19 @@ import (
 	"github.com/angel-one/go-utils/middlewares"
 	"github.com/sinhashubham95/go-example-project/api"
 	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/cache"
 	"github.com/sinhashubham95/go-example-project/utils/configs"
 	"github.com/sinhashubham95/go-example-project/utils/database"
 	"github.com/sinhashubham95/go-example-project/utils/flags"
 	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/sinhashubham95/go-example-project/utils/outbox"
+	"github.com/sinhashubham95/go-example-project/utils/reload"
+	"github.com/sinhashubham95/go-example-project/utils/secrets"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	_ "github.com/sinhashubham95/go-example-project/docs"
 )
 
@@ -27,56 +33,120 @@ import (
 
 // @BasePath /
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+
+// configWarnings are the warnings loading the configs, logged once the logger is ready
+var configWarnings []string
+
 func main() {
 	ctx := context.Background()
 	initConfigs(ctx)
 	initLogger(ctx)
-	initHTTPClient()
+	initHTTPClient(ctx)
+	initSecrets(ctx)
 	initDatabase(ctx)
 	defer closeDatabase(ctx)
+	initCache(ctx)
+	defer closeCache(ctx)
+	initOutbox(ctx)
+	defer closeOutbox(ctx)
+	initReload(ctx)
 	startRouter(ctx)
 }
 
 func initConfigs(ctx context.Context) {
 	// init configs
-	err := configs.Init(flags.BaseConfigPath(), constants.LoggerConfig, constants.ApplicationConfig,
+	err := configs.Init(flags.BaseConfigPath(), flags.Env(), constants.LoggerConfig, constants.ApplicationConfig,
 		constants.DatabaseConfig)
 	if err != nil {
 		log.Fatal(ctx).Err(err).Msg("error initialising configs")
 	}
+	// the typed configs are loaded and validated once, failing with all the problems found
+	c, warnings, err := configs.Load()
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("error loading configs")
+	}
+	configs.SetConfig(c)
+	configWarnings = warnings
 }
 
 func initLogger(ctx context.Context) {
 	// start logger
-	logLevel, err := configs.Get().GetString(constants.LoggerConfig, constants.LogLevelConfigKey)
-	if err != nil {
-		log.Fatal(ctx).Err(err).Msg("error getting log level")
+	log.InitLogger(log.Level(configs.GetConfig().Logger.Level))
+
+	// the unknown keys are logged once the logger is ready, being likely typos
+	for _, key := range configWarnings {
+		log.Warn(ctx).Str(constants.ConfigKeyKey, key).Msg("unknown config key")
+	}
+
+	// the keys overridden by the environment variables are logged once the logger is ready, keeping the secrets out
+	for _, override := range configs.GetOverrides() {
+		log.Info(ctx).Str(constants.ConfigNameKey, override.Config).Str(constants.ConfigKeyKey, override.Key).
+			Str(constants.EnvVarKey, override.Env).Interface(constants.ValueKey,
+			configs.RedactValue(override.Key, override.Value)).
+			Msg("config overridden by the environment")
 	}
-	log.InitLogger(log.Level(logLevel))
 }
 
-func initHTTPClient() {
-	httpclient.Init(
-		httpclient.NewRequestConfig("moxy", configs.Get().GetMapD(constants.ApplicationConfig,
-			"http.moxy", nil)),
-	)
+func initHTTPClient(_ context.Context) {
+	// every entry under http is a request, registered again by the reloads whenever it changes
+	httpclient.Init(httpclient.NewRequestConfigs(configs.GetConfig().Application.GetRequests())...)
+}
+
+func initSecrets(ctx context.Context) {
+	// init secrets
+	err := secrets.InitSecrets(ctx, secrets.Config{KeyFile: configs.GetConfig().Application.Secrets.KeyFile})
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("unable to initialize secrets")
+	}
 }
 
 func initDatabase(ctx context.Context) {
 	// init database
-	err := database.InitDatabase(ctx, database.Config{
-		Server:             configs.Get().GetStringD(constants.DatabaseConfig, constants.DatabaseServerConfigKey, ""),
-		Port:               int(configs.Get().GetIntD(constants.DatabaseConfig, constants.DatabasePortConfigKey, 0)),
-		Name:               configs.Get().GetStringD(constants.DatabaseConfig, constants.DatabaseNameConfigKey, ""),
-		Username:           configs.Get().GetStringD(constants.DatabaseConfig, constants.DatabaseUsernameConfigKey, ""),
-		Password:           configs.Get().GetStringD(constants.DatabaseConfig, constants.DatabasePasswordConfigKey, ""),
-		MaxOpenConnections: int(configs.Get().GetIntD(constants.DatabaseConfig, constants.DatabaseMaxOpenConnectionsKey, 0)),
-		MaxIdleConnections: int(configs.Get().GetIntD(constants.DatabaseConfig, constants.DatabaseMaxIdleConnectionsKey, 0)),
-		ConnectionMaxLifetime: time.Duration(configs.Get().GetIntD(constants.DatabaseConfig,
-			constants.DatabaseConnectionMaxLifetimeInSecondsKey, 0)) * time.Second,
-		ConnectionMaxIdleTime: time.Duration(configs.Get().GetIntD(constants.DatabaseConfig,
-			constants.DatabaseConnectionMaxIdleTimeInSecondsKey, 0)) * time.Second,
-	})
+	c := configs.GetConfig().Database
+	config := database.Config{
+		Dialect:                         c.Dialect,
+		Driver:                          c.Driver,
+		Server:                          c.Server,
+		Port:                            c.Port,
+		Name:                            c.Name,
+		Username:                        c.Username,
+		Password:                        c.Password,
+		MaxOpenConnections:              c.MaxOpenConnections,
+		MaxIdleConnections:              c.MaxIdleConnections,
+		ConnectionMaxLifetime:           time.Duration(c.ConnectionMaxLifetimeInSeconds) * time.Second,
+		ConnectionMaxIdleTime:           time.Duration(c.ConnectionMaxIdleTimeInSeconds) * time.Second,
+		Replicas:                        c.Replicas,
+		ReplicaStrategy:                 c.ReplicaStrategy,
+		ReplicaHealthCheckInterval:      time.Duration(c.ReplicaHealthCheckIntervalInMillis) * time.Millisecond,
+		StartupTimeout:                  time.Duration(c.StartupTimeoutInMillis) * time.Millisecond,
+		StartupBackoff:                  time.Duration(c.StartupBackoffInMillis) * time.Millisecond,
+		HealthCheckInterval:             time.Duration(c.HealthCheckIntervalInMillis) * time.Millisecond,
+		SlowQueryThreshold:              time.Duration(c.SlowQueryThresholdInMillis) * time.Millisecond,
+		CredentialRotationCheckInterval: time.Duration(c.CredentialRotationCheckIntervalInMillis) * time.Millisecond,
+		TLS: database.TLSConfig{
+			Mode:       c.TLS.Mode,
+			CAFile:     c.TLS.CAFile,
+			CertFile:   c.TLS.CertFile,
+			KeyFile:    c.TLS.KeyFile,
+			ServerName: c.TLS.ServerName,
+		},
+		DialTimeout:  time.Duration(c.DialTimeoutInMillis) * time.Millisecond,
+		ReadTimeout:  time.Duration(c.ReadTimeoutInMillis) * time.Millisecond,
+		WriteTimeout: time.Duration(c.WriteTimeoutInMillis) * time.Millisecond,
+		ParseTime:    c.ParseTime,
+		Charset:      c.Charset,
+		Collation:    c.Collation,
+		Params:       c.Params,
+		TransactionRetry: database.RetryPolicy{
+			MaxRetries: c.TransactionMaxRetries,
+			Backoff:    time.Duration(c.TransactionBackoffInMillis) * time.Millisecond,
+		},
+	}
+	log.Info(ctx).Interface(constants.DatabaseConfigKey, configs.RedactStruct(config)).Msg("initializing database")
+	err := database.InitDatabase(ctx, config)
 	if err != nil {
 		log.Fatal(ctx).Err(err).Msg("unable to initialize database")
 	}
@@ -89,6 +159,65 @@ func closeDatabase(ctx context.Context) {
 	}
 }
 
+func initCache(ctx context.Context) {
+	// init cache
+	c := configs.GetConfig().Application.Counter.Cache
+	config := cache.Config{
+		Backend:       c.Backend,
+		Size:          c.Size,
+		TTL:           time.Duration(c.TTLInMillis) * time.Millisecond,
+		RedisAddress:  c.Redis.Address,
+		RedisPassword: c.Redis.Password,
+		RedisDatabase: c.Redis.Database,
+		RedisTimeout:  time.Duration(c.Redis.TimeoutInMillis) * time.Millisecond,
+		RedisPoolSize: c.Redis.PoolSize,
+	}
+	log.Info(ctx).Interface(constants.CacheConfigKey, configs.RedactStruct(config)).Msg("initializing cache")
+	err := cache.InitCache(config)
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("unable to initialize cache")
+	}
+}
+
+func closeCache(ctx context.Context) {
+	err := cache.Close()
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("error closing cache")
+	}
+}
+
+func initOutbox(ctx context.Context) {
+	// init outbox
+	c := configs.GetConfig().Application.Outbox
+	err := outbox.InitOutbox(ctx, outbox.Config{
+		Enabled:      c.Enabled,
+		Sink:         c.Sink,
+		FilePath:     c.FilePath,
+		PollInterval: time.Duration(c.PollIntervalInMillis) * time.Millisecond,
+		BatchSize:    c.BatchSize,
+		Backoff:      time.Duration(c.BackoffInMillis) * time.Millisecond,
+		MaxBackoff:   time.Duration(c.MaxBackoffInMillis) * time.Millisecond,
+	})
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("unable to initialize outbox")
+	}
+}
+
+func closeOutbox(ctx context.Context) {
+	err := outbox.Close()
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("error closing outbox")
+	}
+}
+
+func initReload(ctx context.Context) {
+	// the changes to the config files are validated and applied once everything is initialised
+	err := reload.Init(ctx)
+	if err != nil {
+		log.Fatal(ctx).Err(err).Msg("error listening to the config changes")
+	}
+}
+
 func startRouter(ctx context.Context) {
 	// get router
 	router := api.GetRouter(middlewares.Logger(middlewares.LoggerMiddlewareOptions{}))
@@ -5,3 +5,9 @@ type CounterResponse struct {
 	Key   string `json:"key"`
 	Count int    `json:"count"`
 }
+
+// CounterEvent is the payload of the counter change events published through the outbox
+type CounterEvent struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
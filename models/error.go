@@ -4,4 +4,6 @@ package models
 type ErrorResponse struct {
 	Code        string `json:"code"`
 	Description string `json:"description"`
+	// UpstreamStatus is the status the upstream service responded with, when the error came from it
+	UpstreamStatus int `json:"upstreamStatus,omitempty"`
 }
@@ -0,0 +1,7 @@
+package models
+
+// HealthResponse is the response body for the health, liveness and readiness actuator endpoints
+type HealthResponse struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components,omitempty"`
+}
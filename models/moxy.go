@@ -1,6 +1,23 @@
 package models
 
-// MoxyResponse is the response body for moxy api
+import "errors"
+
+// MoxyResponse is the response body for moxy api, the data being the message of the moxy service
 type MoxyResponse struct {
 	Data string `json:"data"`
+	// Fallback is the strategy of the fallback response served in place of the failed one, empty otherwise
+	Fallback string `json:"-"`
+}
+
+// MoxyMessage is the response body of the moxy service
+type MoxyMessage struct {
+	Message string `json:"message"`
+}
+
+// Validate is used to validate the response body
+func (r MoxyMessage) Validate() error {
+	if r.Message == "" {
+		return errors.New("message cannot be empty")
+	}
+	return nil
 }
@@ -0,0 +1,6 @@
+package models
+
+// ReplayOutboxResponse is the response for the outbox replay request
+type ReplayOutboxResponse struct {
+	Replayed int64 `json:"replayed"`
+}
@@ -0,0 +1,16 @@
+-- outbox of the counter change events, written in the same transaction as the change
+-- the times are epoch milliseconds, use bigserial for the id on postgres and integer primary key on sqlite
+create table if not exists outbox
+(
+    id              bigint       not null auto_increment primary key,
+    event_key       varchar(255) not null,
+    event_type      varchar(64)  not null,
+    payload         text         not null,
+    created_at      bigint       not null,
+    published_at    bigint       null,
+    attempts        int          not null default 0,
+    next_attempt_at bigint       not null,
+    last_error      text         not null,
+    index outbox_pending (published_at, id),
+    index outbox_key (event_key, id)
+);
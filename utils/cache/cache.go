@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"time"
+)
+
+// Cache is the set of methods for a cache backend
+type Cache interface {
+	// Get is used to get the value stored against the key, the boolean reports whether the key was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set is used to store the value against the key for the configured ttl
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete is used to remove the key from the cache
+	Delete(ctx context.Context, key string) error
+	// Close is used to release any resources held by the cache
+	Close() error
+}
+
+// Config is the set of configurable parameters for the cache
+type Config struct {
+	Backend       string        `json:"backend"`
+	Size          int           `json:"size"`
+	TTL           time.Duration `json:"ttl"`
+	RedisAddress  string        `json:"redisAddress"`
+	RedisPassword string        `json:"redisPassword"`
+	RedisDatabase int           `json:"redisDatabase"`
+	RedisTimeout  time.Duration `json:"redisTimeout"`
+	RedisPoolSize int           `json:"redisPoolSize"`
+}
+
+var cache Cache = noopCache{}
+
+// InitCache is used to initialise the cache with the configured backend
+func InitCache(config Config) error {
+	c, err := New(config)
+	if err != nil {
+		return err
+	}
+	cache = c
+	return nil
+}
+
+// New is used to create a new instrumented cache with the configured backend
+func New(config Config) (Cache, error) {
+	switch config.Backend {
+	case constants.NoCacheBackend:
+		return noopCache{}, nil
+	case constants.LRUCacheBackend:
+		return newInstrumentedCache(NewLRU(config.Size, config.TTL)), nil
+	case constants.RedisCacheBackend:
+		return newInstrumentedCache(NewRedis(config.RedisAddress, config.RedisPassword, config.RedisDatabase,
+			config.TTL, config.RedisTimeout, config.RedisPoolSize)), nil
+	default:
+		return nil, fmt.Errorf("invalid cache backend %s provided", config.Backend)
+	}
+}
+
+// Get is used to get the cache instance
+func Get() Cache {
+	return cache
+}
+
+// Close is used to close the cache
+func Close() error {
+	return cache.Close()
+}
+
+type instrumentedCache struct {
+	cache   Cache
+	hits    *metrics.Counter
+	misses  *metrics.Counter
+	errors  *metrics.Counter
+	latency *metrics.Histogram
+}
+
+func newInstrumentedCache(cache Cache) Cache {
+	return &instrumentedCache{
+		cache:   cache,
+		hits:    metrics.GetCounter(constants.CacheHitsMetric),
+		misses:  metrics.GetCounter(constants.CacheMissesMetric),
+		errors:  metrics.GetCounter(constants.CacheErrorsMetric),
+		latency: metrics.GetHistogram(constants.CacheLatencyMetric),
+	}
+}
+
+func (c *instrumentedCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	start := time.Now()
+	value, ok, err := c.cache.Get(ctx, key)
+	c.latency.ObserveDuration(time.Since(start))
+	switch {
+	case err != nil:
+		c.errors.Inc()
+	case ok:
+		c.hits.Inc()
+	default:
+		c.misses.Inc()
+	}
+	return value, ok, err
+}
+
+func (c *instrumentedCache) Set(ctx context.Context, key string, value []byte) error {
+	err := c.cache.Set(ctx, key, value)
+	if err != nil {
+		c.errors.Inc()
+	}
+	return err
+}
+
+func (c *instrumentedCache) Delete(ctx context.Context, key string) error {
+	err := c.cache.Delete(ctx, key)
+	if err != nil {
+		c.errors.Inc()
+	}
+	return err
+}
+
+func (c *instrumentedCache) Close() error {
+	return c.cache.Close()
+}
+
+// noopCache is used when caching is disabled, every read is a miss
+type noopCache struct{}
+
+func (noopCache) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (noopCache) Set(context.Context, string, []byte) error {
+	return nil
+}
+
+func (noopCache) Delete(context.Context, string) error {
+	return nil
+}
+
+func (noopCache) Close() error {
+	return nil
+}
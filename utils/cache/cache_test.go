@@ -0,0 +1,198 @@
+package cache_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/cache"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is an in-process server speaking just enough of the redis protocol for the cache
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	expiry   map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	f := &fakeRedis{listener: listener, values: make(map[string]string), expiry: make(map[string]time.Time)}
+	go f.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		_, _ = io.WriteString(conn, f.execute(args))
+	}
+}
+
+func (f *fakeRedis) execute(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := f.values[args[1]]
+		if expiresAt, expires := f.expiry[args[1]]; !ok || (expires && time.Now().After(expiresAt)) {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		f.values[args[1]] = args[2]
+		delete(f.expiry, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			millis, _ := strconv.Atoi(args[4])
+			f.expiry[args[1]] = time.Now().Add(time.Duration(millis) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := f.values[args[1]]
+		delete(f.values, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:length]))
+	}
+	return args, nil
+}
+
+func testCache(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+
+	_, ok, err := c.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, c.Set(ctx, "a", []byte("1")))
+	value, ok, err := c.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	assert.NoError(t, c.Delete(ctx, "a"))
+	_, ok, err = c.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, c.Set(ctx, "b", []byte("2")))
+	time.Sleep(60 * time.Millisecond)
+	_, ok, err = c.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, c.Close())
+}
+
+func TestLRU(t *testing.T) {
+	testCache(t, cache.NewLRU(10, 50*time.Millisecond))
+}
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(2, 0)
+	assert.NoError(t, c.Set(ctx, "a", []byte("1")))
+	assert.NoError(t, c.Set(ctx, "b", []byte("2")))
+	_, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.NoError(t, c.Set(ctx, "c", []byte("3")))
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok)
+	_, ok, _ = c.Get(ctx, "a")
+	assert.True(t, ok)
+}
+
+func TestRedis(t *testing.T) {
+	f := newFakeRedis(t)
+	testCache(t, cache.NewRedis(f.listener.Addr().String(), "", 0, 50*time.Millisecond, time.Second, 2))
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	c := cache.NewRedis("127.0.0.1:1", "", 0, time.Second, 100*time.Millisecond, 1)
+	_, ok, err := c.Get(context.Background(), "a")
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestNewMetrics(t *testing.T) {
+	c, err := cache.New(cache.Config{Backend: constants.LRUCacheBackend, Size: 10})
+	assert.NoError(t, err)
+	hits := metrics.GetCounter(constants.CacheHitsMetric).Value()
+	misses := metrics.GetCounter(constants.CacheMissesMetric).Value()
+
+	ctx := context.Background()
+	_, _, _ = c.Get(ctx, "a")
+	assert.NoError(t, c.Set(ctx, "a", []byte("1")))
+	_, _, _ = c.Get(ctx, "a")
+
+	assert.Equal(t, hits+1, metrics.GetCounter(constants.CacheHitsMetric).Value())
+	assert.Equal(t, misses+1, metrics.GetCounter(constants.CacheMissesMetric).Value())
+}
+
+func TestNewInvalidBackend(t *testing.T) {
+	_, err := cache.New(cache.Config{Backend: "memcached"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// errRedisNil is the reply for a key which does not exist
+var errRedisNil = errors.New("redis nil reply")
+
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+type redisConnection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+type redisCache struct {
+	address  string
+	password string
+	database int
+	ttl      time.Duration
+	timeout  time.Duration
+	idle     chan *redisConnection
+}
+
+// NewRedis is used to create a new cache backed by a server speaking the redis protocol
+// Connections are dialled lazily and at most poolSize idle connections are kept around.
+func NewRedis(address, password string, database int, ttl, timeout time.Duration, poolSize int) Cache {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	return &redisCache{
+		address:  address,
+		password: password,
+		database: database,
+		ttl:      ttl,
+		timeout:  timeout,
+		idle:     make(chan *redisConnection, poolSize),
+	}
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if errors.Is(err, errRedisNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected redis reply %v for get", reply)
+	}
+	return value, true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte) error {
+	args := []string{"SET", key, string(value)}
+	if c.ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(int64(c.ttl/time.Millisecond), 10))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+func (c *redisCache) Delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, "DEL", key)
+	return err
+}
+
+func (c *redisCache) Close() error {
+	for {
+		select {
+		case connection := <-c.idle:
+			_ = connection.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *redisCache) do(ctx context.Context, args ...string) (interface{}, error) {
+	connection, err := c.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := connection.do(ctx, c.timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &replyErr) {
+		// the connection is in an unknown state, so it cannot be reused
+		_ = connection.conn.Close()
+		return nil, err
+	}
+	c.putConnection(connection)
+	return reply, err
+}
+
+func (c *redisCache) getConnection(ctx context.Context) (*redisConnection, error) {
+	select {
+	case connection := <-c.idle:
+		return connection, nil
+	default:
+	}
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	connection := &redisConnection{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}
+	if c.password != "" {
+		if _, err = connection.do(ctx, c.timeout, "AUTH", c.password); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if c.database != 0 {
+		if _, err = connection.do(ctx, c.timeout, "SELECT", strconv.Itoa(c.database)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return connection, nil
+}
+
+func (c *redisCache) putConnection(connection *redisConnection) {
+	select {
+	case c.idle <- connection:
+	default:
+		_ = connection.conn.Close()
+	}
+}
+
+func (r *redisConnection) do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if timeout > 0 && (!ok || time.Until(deadline) > timeout) {
+		deadline = time.Now().Add(timeout)
+	}
+	err := r.conn.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+	err = r.write(args)
+	if err != nil {
+		return nil, err
+	}
+	return r.read()
+}
+
+func (r *redisConnection) write(args []string) error {
+	_, err := fmt.Fprintf(r.writer, "*%d\r\n", len(args))
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		_, err = fmt.Fprintf(r.writer, "$%d\r\n%s\r\n", len(arg), arg)
+		if err != nil {
+			return err
+		}
+	}
+	return r.writer.Flush()
+}
+
+func (r *redisConnection) read() (interface{}, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("invalid redis reply %q", line)
+	}
+	payload := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, errRedisNil
+		}
+		data := make([]byte, length+2)
+		_, err = io.ReadFull(r.reader, data)
+		if err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+	case '*':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, errRedisNil
+		}
+		values := make([]interface{}, 0, length)
+		for i := 0; i < length; i++ {
+			value, err := r.read()
+			if err != nil && !errors.Is(err, errRedisNil) {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("invalid redis reply %q", line)
+	}
+}
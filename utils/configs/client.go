@@ -0,0 +1,465 @@
+package configs
+
+import (
+	"fmt"
+	configs "github.com/angel-one/go-config-client"
+	"strconv"
+	"strings"
+)
+
+// layeredClient is the config client serving the keys of the configs from their layers
+// The environment variable overriding a key takes precedence over the overlay of the environment, which takes
+// precedence over the config itself. The maps are merged across the layers while the rest of the values are replaced,
+// and the keys are matched regardless of their case, like the client does.
+type layeredClient struct {
+	configs.Client
+	// overlays are the names of the overlays of the configs, the ones loaded for the environment
+	overlays  map[string]string
+	overrides map[string]map[string]interface{}
+}
+
+func newLayeredClient(client configs.Client, overlays map[string]string, list []Override) configs.Client {
+	if len(overlays) == 0 && len(list) == 0 {
+		return client
+	}
+	c := &layeredClient{Client: client, overlays: overlays, overrides: make(map[string]map[string]interface{})}
+	for _, o := range list {
+		if c.overrides[o.Config] == nil {
+			c.overrides[o.Config] = make(map[string]interface{})
+		}
+		c.overrides[o.Config][o.Key] = o.Value
+	}
+	return c
+}
+
+// isLayered reports whether the key, or any key under it, is in the overlay of the config or overridden
+func (c *layeredClient) isLayered(config, key string) bool {
+	if overlay, ok := c.overlays[config]; ok {
+		if value, _ := c.Client.Get(overlay, key); value != nil {
+			return true
+		}
+	}
+	return c.isOverridden(config, key)
+}
+
+// isOverridden reports whether the key, or any key under it, is overridden
+func (c *layeredClient) isOverridden(config, key string) bool {
+	key = strings.ToLower(key)
+	for k := range c.overrides[config] {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *layeredClient) Get(config, key string) (interface{}, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.Get(config, key)
+	}
+	key = strings.ToLower(key)
+	if value, ok := c.overrides[config][key]; ok {
+		return value, nil
+	}
+	value, err := c.Client.Get(config, key)
+	if err != nil {
+		return nil, err
+	}
+	if overlay, ok := c.overlays[config]; ok {
+		if o, _ := c.Client.Get(overlay, key); o != nil {
+			value = merge(value, o)
+		}
+	}
+	if !c.isOverridden(config, key) {
+		return value, nil
+	}
+	m := copyMap(value)
+	for k, v := range c.overrides[config] {
+		if strings.HasPrefix(k, key+".") {
+			set(m, strings.Split(strings.TrimPrefix(k, key+"."), "."), v)
+		}
+	}
+	return m, nil
+}
+
+func (c *layeredClient) GetD(config, key string, defaultValue interface{}) interface{} {
+	value, err := c.Get(config, key)
+	if err != nil || value == nil {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetInt(config, key string) (int64, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetInt(config, key)
+	}
+	value, err := c.Get(config, key)
+	return toInt(value), err
+}
+
+func (c *layeredClient) GetIntD(config, key string, defaultValue int64) int64 {
+	value, err := c.GetInt(config, key)
+	if err != nil || value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetFloat(config, key string) (float64, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetFloat(config, key)
+	}
+	value, err := c.Get(config, key)
+	return toFloat(value), err
+}
+
+func (c *layeredClient) GetFloatD(config, key string, defaultValue float64) float64 {
+	value, err := c.GetFloat(config, key)
+	if err != nil || value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetString(config, key string) (string, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetString(config, key)
+	}
+	value, err := c.Get(config, key)
+	if err != nil || value == nil {
+		return "", err
+	}
+	return fmt.Sprint(value), nil
+}
+
+func (c *layeredClient) GetStringD(config, key string, defaultValue string) string {
+	value, err := c.GetString(config, key)
+	if err != nil || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetBool(config, key string) (bool, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetBool(config, key)
+	}
+	value, err := c.Get(config, key)
+	return toBool(value), err
+}
+
+func (c *layeredClient) GetBoolD(config, key string, defaultValue bool) bool {
+	value, err := c.GetBool(config, key)
+	if err != nil || !value {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetSlice(config, key string) ([]interface{}, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetSlice(config, key)
+	}
+	value, err := c.Get(config, key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		return v, nil
+	case string:
+		list, _ := coerce(v, []interface{}{})
+		return list.([]interface{}), nil
+	default:
+		return nil, fmt.Errorf("invalid value type")
+	}
+}
+
+func (c *layeredClient) GetSliceD(config, key string, defaultValue []interface{}) []interface{} {
+	value, err := c.GetSlice(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetIntSlice(config, key string) ([]int64, error) {
+	list, err := c.GetSlice(config, key)
+	result := make([]int64, 0, len(list))
+	for _, value := range list {
+		result = append(result, toInt(value))
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetIntSliceD(config, key string, defaultValue []int64) []int64 {
+	value, err := c.GetIntSlice(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetFloatSlice(config, key string) ([]float64, error) {
+	list, err := c.GetSlice(config, key)
+	result := make([]float64, 0, len(list))
+	for _, value := range list {
+		result = append(result, toFloat(value))
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetFloatSliceD(config, key string, defaultValue []float64) []float64 {
+	value, err := c.GetFloatSlice(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetStringSlice(config, key string) ([]string, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetStringSlice(config, key)
+	}
+	list, err := c.GetSlice(config, key)
+	result := make([]string, 0, len(list))
+	for _, value := range list {
+		result = append(result, fmt.Sprint(value))
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetStringSliceD(config, key string, defaultValue []string) []string {
+	value, err := c.GetStringSlice(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetBoolSlice(config, key string) ([]bool, error) {
+	list, err := c.GetSlice(config, key)
+	result := make([]bool, 0, len(list))
+	for _, value := range list {
+		result = append(result, toBool(value))
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetBoolSliceD(config, key string, defaultValue []bool) []bool {
+	value, err := c.GetBoolSlice(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetMap(config, key string) (map[string]interface{}, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetMap(config, key)
+	}
+	value, err := c.Get(config, key)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := value.(map[string]interface{})
+	return m, nil
+}
+
+func (c *layeredClient) GetMapD(config, key string, defaultValue map[string]interface{}) map[string]interface{} {
+	value, err := c.GetMap(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetIntMap(config, key string) (map[string]int64, error) {
+	m, err := c.GetMap(config, key)
+	result := make(map[string]int64, len(m))
+	for k, value := range m {
+		result[k] = toInt(value)
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetIntMapD(config, key string, defaultValue map[string]int64) map[string]int64 {
+	value, err := c.GetIntMap(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetFloatMap(config, key string) (map[string]float64, error) {
+	m, err := c.GetMap(config, key)
+	result := make(map[string]float64, len(m))
+	for k, value := range m {
+		result[k] = toFloat(value)
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetFloatMapD(config, key string, defaultValue map[string]float64) map[string]float64 {
+	value, err := c.GetFloatMap(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetStringMap(config, key string) (map[string]string, error) {
+	if !c.isLayered(config, key) {
+		return c.Client.GetStringMap(config, key)
+	}
+	m, err := c.GetMap(config, key)
+	result := make(map[string]string, len(m))
+	for k, value := range m {
+		result[k] = fmt.Sprint(value)
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetStringMapD(config, key string, defaultValue map[string]string) map[string]string {
+	value, err := c.GetStringMap(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c *layeredClient) GetBoolMap(config, key string) (map[string]bool, error) {
+	m, err := c.GetMap(config, key)
+	result := make(map[string]bool, len(m))
+	for k, value := range m {
+		result[k] = toBool(value)
+	}
+	return result, err
+}
+
+func (c *layeredClient) GetBoolMapD(config, key string, defaultValue map[string]bool) map[string]bool {
+	value, err := c.GetBoolMap(config, key)
+	if err != nil || len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// copyMap is used to get a deep copy of the map, so that setting the overrides does not change the one of the client
+func copyMap(value interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	switch m := value.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			result[k] = copyValue(v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			result[fmt.Sprint(k)] = copyValue(v)
+		}
+	}
+	return result
+}
+
+func copyValue(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return copyMap(value)
+	default:
+		return value
+	}
+}
+
+// set is used to set the value at the path in the map, matching the keys regardless of their case
+func set(m map[string]interface{}, path []string, value interface{}) {
+	key := path[0]
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			key = k
+			break
+		}
+	}
+	if len(path) == 1 {
+		m[key] = value
+		return
+	}
+	next, ok := m[key].(map[string]interface{})
+	if !ok {
+		next = make(map[string]interface{})
+		m[key] = next
+	}
+	set(next, path[1:], value)
+}
+
+func toInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	default:
+		return 0
+	}
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+func toBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	default:
+		return false
+	}
+}
+
+// merge is used to merge the overlay into the value, the maps being merged and the rest replaced
+func merge(value, overlay interface{}) interface{} {
+	if !isMap(value) || !isMap(overlay) {
+		return copyValue(overlay)
+	}
+	m := copyMap(value)
+	for k, v := range copyMap(overlay) {
+		key := k
+		for existing := range m {
+			if strings.EqualFold(existing, k) {
+				key = existing
+				break
+			}
+		}
+		m[key] = merge(m[key], v)
+	}
+	return m
+}
+
+func isMap(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	default:
+		return false
+	}
+}
@@ -2,24 +2,61 @@ package configs
 
 import (
 	configs "github.com/angel-one/go-config-client"
+	"os"
+	"path/filepath"
+	"sync"
 )
 
-var client configs.Client
+var (
+	client    configs.Client
+	directory string
+	// configNamesLoaded and configOverlays are the configs initialised, along with the overlays loaded for them
+	configNamesLoaded []string
+	configOverlays    map[string]string
+	overrides         []Override
 
-// Init is used to initialize the configs
-func Init(directory string, configNames ...string) error {
-	var err error
-	client, err = configs.New(configs.Options{
+	mu        sync.RWMutex
+	listeners map[string][]func()
+)
+
+// Init is used to initialize the configs, along with their overlays for the environment when it is set
+// The overlay of a config is the file named after it and the environment, like application-prod.yml, merged into it.
+// Any key of the configs can be overridden by the environment variable named after it, see GetEnvName.
+func Init(dir, env string, configNames ...string) error {
+	overlays := make(map[string]string)
+	names := append([]string{}, configNames...)
+	if env != "" {
+		for _, name := range configNames {
+			overlay := getOverlayName(name, env)
+			if _, ok := getPath(dir, overlay); ok {
+				overlays[name] = overlay
+				names = append(names, overlay)
+			}
+		}
+	}
+	o, err := loadOverrides(dir, configNames, overlays)
+	if err != nil {
+		return err
+	}
+	c, err := configs.New(configs.Options{
 		Provider: configs.FileBased,
 		Params: map[string]interface{}{
-			"configsDirectory": directory,
-			"configNames":      configNames,
+			"configsDirectory": dir,
+			"configNames":      names,
 			"configType":       "yaml",
 		},
 	})
 	if err != nil {
 		return err
 	}
+	client = newLayeredClient(c, overlays, o)
+	mu.Lock()
+	directory = dir
+	configNamesLoaded = append([]string{}, configNames...)
+	configOverlays = overlays
+	overrides = o
+	listeners = make(map[string][]func())
+	mu.Unlock()
 	return nil
 }
 
@@ -27,3 +64,71 @@ func Init(directory string, configNames ...string) error {
 func Get() configs.Client {
 	return client
 }
+
+// AddChangeListener is used to get the listener called every time the config changes
+// Unlike the client, which keeps a single listener per config, any number of listeners can be added.
+func AddChangeListener(config string, listener func()) error {
+	mu.Lock()
+	defer mu.Unlock()
+	// the client is only watched for the first listener, which is kept once the watch is registered
+	if len(listeners[config]) == 0 {
+		if err := watch(config); err != nil {
+			return err
+		}
+	}
+	listeners[config] = append(listeners[config], listener)
+	return nil
+}
+
+func watch(config string) error {
+	notify := func(...interface{}) {
+		notifyListeners(config)
+	}
+	// the file based client notifies the listeners by the path of the changed file rather than the config name,
+	// and the config changes along with its overlay
+	names := []string{config}
+	if overlay, ok := configOverlays[config]; ok {
+		names = append(names, overlay)
+	}
+	for _, name := range names {
+		if path, ok := getConfigPath(name); ok {
+			if err := client.AddChangeListener(path, notify); err != nil {
+				return err
+			}
+		}
+		if err := client.AddChangeListener(name, notify); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notifyListeners(config string) {
+	mu.RLock()
+	l := append([]func(){}, listeners[config]...)
+	mu.RUnlock()
+	for _, listener := range l {
+		listener()
+	}
+}
+
+func getOverlayName(config, env string) string {
+	return config + "-" + env
+}
+
+func getConfigPath(config string) (string, bool) {
+	return getPath(directory, config)
+}
+
+func getPath(dir, config string) (string, bool) {
+	for _, extension := range []string{".yaml", ".yml"} {
+		path, err := filepath.Abs(filepath.Join(dir, config+extension))
+		if err != nil {
+			continue
+		}
+		if _, err = os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
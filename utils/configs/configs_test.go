@@ -0,0 +1,321 @@
+package configs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goConfigs "github.com/angel-one/go-config-client"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/cache"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeListeners(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "application.yml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("http:\n  moxy:\n    url: http://a\n"), 0644))
+	assert.NoError(t, configs.Init(directory, "", "application"))
+
+	first, second := int32(0), int32(0)
+	assert.NoError(t, configs.AddChangeListener("application", func() {
+		atomic.AddInt32(&first, 1)
+	}))
+	assert.NoError(t, configs.AddChangeListener("application", func() {
+		atomic.AddInt32(&second, 1)
+	}))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("http:\n  moxy:\n    url: http://b\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&first) > 0 && atomic.LoadInt32(&second) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "http://b", configs.Get().GetStringD("application", "http.moxy.url", ""))
+}
+
+// unwatchedClient fails to watch the configs
+type unwatchedClient struct {
+	goConfigs.Client
+}
+
+func (unwatchedClient) AddChangeListener(string, goConfigs.ChangeListener) error {
+	return errors.New("watch failed")
+}
+
+func TestChangeListenerNotWatched(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "application.yml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("http:\n  moxy:\n    url: http://a\n"), 0644))
+	assert.NoError(t, configs.Init(directory, "", "application"))
+
+	// the listener failing to be watched is not kept, so the next one gets the config watched
+	failed, added := int32(0), int32(0)
+	previous := configs.SetClient(unwatchedClient{Client: configs.Get()})
+	assert.Error(t, configs.AddChangeListener("application", func() {
+		atomic.AddInt32(&failed, 1)
+	}))
+	configs.SetClient(previous)
+	assert.NoError(t, configs.AddChangeListener("application", func() {
+		atomic.AddInt32(&added, 1)
+	}))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("http:\n  moxy:\n    url: http://b\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&added) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&failed))
+}
+
+func TestEnvOverrides(t *testing.T) {
+	directory := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "database.yml"),
+		[]byte("maxOpenConnections: 10\npassword: env:DATABASE_PASSWORD\nreplicas: [a]\nretry_count: 1\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "application.yml"),
+		[]byte("http:\n  moxy:\n    url: http://a\n    timeoutinmillis: 1000\n    cache:\n      enabled: true\n"), 0644))
+	env := map[string]string{
+		"APP_DATABASE_MAXOPENCONNECTIONS":           "20",
+		"APP_DATABASE_PASSWORD":                     "yes",
+		"APP_DATABASE_REPLICAS":                     "b, c",
+		"APP_DATABASE_RETRY_COUNT":                  "3",
+		"APP_DATABASE_NAME":                         "counters",
+		"APP_DATABASE_SCHEMA":                       "007",
+		"APP_APPLICATION_HTTP_MOXY_TIMEOUTINMILLIS": "500",
+		"APP_APPLICATION_HTTP_MOXY_CACHE_ENABLED":   "false",
+		"APP_APPLICATION_HTTP_MOXY_RETRYCOUNT":      "2",
+		"APP_APPLICATION_HTTP_MOXY_HEDGE_ENABLED":   "true",
+		"APP_APPLICATION_HTTP_MOXY_JITTERFACTOR":    "1.5",
+	}
+	for name, value := range env {
+		assert.NoError(t, os.Setenv(name, value))
+	}
+	defer func() {
+		for name := range env {
+			_ = os.Unsetenv(name)
+		}
+	}()
+	assert.NoError(t, configs.Init(directory, "", "application", "database"))
+
+	c := configs.Get()
+	assert.Equal(t, int64(20), c.GetIntD("database", "maxOpenConnections", 0))
+	assert.Equal(t, "yes", c.GetStringD("database", "password", ""))
+	assert.Equal(t, []string{"b", "c"}, c.GetStringSliceD("database", "replicas", nil))
+	assert.Equal(t, int64(3), c.GetIntD("database", "retry_count", 0))
+	assert.Equal(t, "counters", c.GetStringD("database", "name", ""))
+	// the maps are served with the keys overridden in them, keeping the rest as they are
+	moxy := c.GetMapD("application", "http", nil)["moxy"].(map[string]interface{})
+	assert.Equal(t, "http://a", moxy["url"])
+	assert.Equal(t, 500, moxy["timeoutinmillis"])
+	assert.Equal(t, false, moxy["cache"].(map[string]interface{})["enabled"])
+	assert.Equal(t, int64(500), c.GetIntD("application", "http.moxy.timeoutinmillis", 0))
+	// the keys in none of the files get the bool or the number they read as, unless it would change them
+	assert.Equal(t, 2, moxy["retrycount"])
+	assert.Equal(t, true, moxy["hedge"].(map[string]interface{})["enabled"])
+	assert.Equal(t, 1.5, moxy["jitterfactor"])
+	assert.Equal(t, "007", c.GetStringD("database", "schema", ""))
+
+	overrides := configs.GetOverrides()
+	assert.Len(t, overrides, len(env))
+	assert.Equal(t, "APP_APPLICATION_HTTP_MOXY_CACHE_ENABLED", overrides[0].Env)
+	assert.Equal(t, "http.moxy.cache.enabled", overrides[0].Key)
+	assert.True(t, configs.IsSecret("password"))
+	assert.False(t, configs.IsSecret("maxOpenConnections"))
+	assert.Equal(t, "APP_DATABASE_MAXOPENCONNECTIONS", configs.GetEnvName("database", "maxOpenConnections"))
+
+	// the values which cannot be converted to the type of the ones they override fail the initialisation
+	assert.NoError(t, os.Setenv("APP_DATABASE_MAXOPENCONNECTIONS", "many"))
+	assert.Error(t, configs.Init(directory, "", "application", "database"))
+}
+
+func TestAmbiguousEnvOverride(t *testing.T) {
+	directory := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "database.yml"),
+		[]byte("retry_count: 1\nretry:\n  count: 2\n"), 0644))
+	assert.NoError(t, os.Setenv("APP_DATABASE_RETRY_COUNT", "3"))
+	defer func() {
+		_ = os.Unsetenv("APP_DATABASE_RETRY_COUNT")
+	}()
+
+	// the variable names both the keys, so neither of them is picked over the other
+	err := configs.Init(directory, "", "database")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "retry.count, retry_count")
+}
+
+func TestOverlays(t *testing.T) {
+	directory := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "application.yml"), []byte(
+		"http:\n  moxy:\n    url: http://a\n    timeoutinmillis: 1000\n    headers: [a, b]\nlevel: debug\n"), 0644))
+	overlay := filepath.Join(directory, "application-prod.yml")
+	assert.NoError(t, ioutil.WriteFile(overlay, []byte(
+		"http:\n  moxy:\n    url: http://b\n    headers: [c]\n  outbox:\n    url: http://c\n"), 0644))
+	assert.NoError(t, os.Setenv("APP_APPLICATION_HTTP_MOXY_TIMEOUTINMILLIS", "500"))
+	defer func() {
+		_ = os.Unsetenv("APP_APPLICATION_HTTP_MOXY_TIMEOUTINMILLIS")
+	}()
+
+	// without the environment the overlay is left out
+	assert.NoError(t, configs.Init(directory, "", "application"))
+	assert.Equal(t, "http://a", configs.Get().GetStringD("application", "http.moxy.url", ""))
+
+	// the nested maps are merged, the lists replaced, and the environment variables take precedence over both
+	assert.NoError(t, configs.Init(directory, "prod", "application"))
+	c := configs.Get()
+	assert.Equal(t, "http://b", c.GetStringD("application", "http.moxy.url", ""))
+	assert.Equal(t, int64(500), c.GetIntD("application", "http.moxy.timeoutinmillis", 0))
+	assert.Equal(t, []string{"c"}, c.GetStringSliceD("application", "http.moxy.headers", nil))
+	assert.Equal(t, "debug", c.GetStringD("application", "level", ""))
+	http := c.GetMapD("application", "http", nil)
+	assert.Equal(t, "http://c", http["outbox"].(map[string]interface{})["url"])
+	moxy := http["moxy"].(map[string]interface{})
+	assert.Equal(t, "http://b", moxy["url"])
+	assert.Equal(t, 500, moxy["timeoutinmillis"])
+
+	effective, err := configs.GetEffective("application")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"http": map[string]interface{}{
+			"moxy":   map[string]interface{}{"url": "http://b", "timeoutinmillis": 500, "headers": []interface{}{"c"}},
+			"outbox": map[string]interface{}{"url": "http://c"},
+		},
+		"level": "debug",
+	}, effective)
+	assert.Equal(t, map[string]interface{}{"password": "[REDACTED]", "db": map[string]interface{}{"token": ""}},
+		configs.Redact(map[string]interface{}{"password": "a", "db": map[string]interface{}{"token": ""}}))
+
+	// the listeners of the config are called when its overlay changes
+	changed := int32(0)
+	assert.NoError(t, configs.AddChangeListener("application", func() {
+		atomic.AddInt32(&changed, 1)
+	}))
+	assert.NoError(t, ioutil.WriteFile(overlay, []byte("http:\n  moxy:\n    url: http://d\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&changed) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "http://d", configs.Get().GetStringD("application", "http.moxy.url", ""))
+}
+
+func TestLoad(t *testing.T) {
+	// the configs in the resources are valid
+	assert.NoError(t, configs.Init("../../resources", "", "logger", "application", "database"))
+	c, warnings, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "mysql", c.Database.Dialect)
+	assert.Equal(t, 5000, c.Application.Counter.QueryTimeoutInMillis)
+	assert.Equal(t, "lru", c.Application.Counter.Cache.Backend)
+	assert.NotEmpty(t, c.Application.HTTP)
+
+	directory := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "logger.yml"), []byte("level: loud\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "application.yml"), []byte(
+		"counter:\n  queryTimeoutInMillis: -1\n  cache:\n    backend: redis\nhttp:\n  moxy:\n    method: get\n"),
+		0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "database.yml"), []byte(
+		"dialect: mysql\nserver: localhost\nport: abc\nname: counter\nmaxOpenConnections: 5\n"+
+			"maxIdleonnections: 10\nMAXIDLECONNECTIONS: 10\nreplicaStrategy: random\n"), 0644))
+
+	// the keys are matched regardless of their case, the unknown ones warned and all the problems aggregated
+	assert.NoError(t, configs.Init(directory, "", "logger", "application", "database"))
+	c, warnings, err = configs.Load()
+	assert.Equal(t, []string{"database.maxIdleonnections"}, warnings)
+	var validationErr *configs.ValidationError
+	if !assert.True(t, errors.As(err, &validationErr)) {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{
+		"database.port: abc cannot be decoded as int",
+		`logger.level: "loud" should be one of trace, debug, info, warn, error, fatal, panic`,
+		"application.counter.queryTimeoutInMillis: cannot be negative",
+		"application.counter.cache.redis.address: is required for the redis cache",
+		"application.http.moxy.url: should be an absolute http or https url",
+		"database.maxIdleConnections: cannot be more than the max open connections",
+		"database.port: should be between 1 and 65535",
+		`database.replicaStrategy: "random" should be one of roundRobin, leastLatency`,
+	}, validationErr.Problems)
+	assert.Equal(t, 10, c.Database.MaxIdleConnections)
+
+	// the typed configs are the defaults when none are set
+	configs.SetConfig(nil)
+	assert.Equal(t, "roundRobin", configs.GetConfig().Database.ReplicaStrategy)
+}
+
+func TestRedaction(t *testing.T) {
+	for key, secret := range map[string]bool{
+		"database.password":                       true,
+		"redisPassword":                           true,
+		"http.moxy.auth.clientSecret":             true,
+		"http.moxy.auth.token":                    true,
+		"http.moxy.auth.key":                      true,
+		"http.moxy.auth.tokenUrl":                 false,
+		"secrets.keyFile":                         false,
+		"credentialRotationCheckIntervalInMillis": false,
+		"maxOpenConnections":                      false,
+	} {
+		assert.Equal(t, secret, configs.IsSecret(key), key)
+	}
+	assert.Equal(t, "[REDACTED]", configs.RedactValue("password", "pass"))
+	assert.Equal(t, "[REDACTED]", configs.RedactValue("password", "enc:abc"))
+	assert.Equal(t, "file:/secret", configs.RedactValue("password", "file:/secret"))
+	assert.Equal(t, "env:DATABASE_PASSWORD", configs.RedactValue("password", "env:DATABASE_PASSWORD"))
+	assert.Equal(t, "", configs.RedactValue("password", ""))
+	assert.Equal(t, "pass", configs.RedactValue("username", "pass"))
+	assert.Equal(t, map[string]interface{}{"redisPassword": "[REDACTED]", "redisPoolSize": float64(1)},
+		configs.RedactStruct(struct {
+			RedisPassword string `json:"redisPassword"`
+			RedisPoolSize int    `json:"redisPoolSize"`
+		}{RedisPassword: "pass", RedisPoolSize: 1}))
+}
+
+func TestRedactedConfigs(t *testing.T) {
+	// the configs are logged with the secrets redacted by the central policy, keeping the references to where they are
+	redacted := configs.RedactStruct(database.Config{Username: "root", Password: "pass"}).(map[string]interface{})
+	assert.Equal(t, "root", redacted["username"])
+	assert.Equal(t, constants.RedactedValue, redacted["password"])
+	redacted = configs.RedactStruct(database.Config{Password: "env:DATABASE_PASSWORD"}).(map[string]interface{})
+	assert.Equal(t, "env:DATABASE_PASSWORD", redacted["password"])
+	cacheConfig := cache.Config{RedisAddress: "localhost:6379", RedisPassword: "pass"}
+	redacted = configs.RedactStruct(cacheConfig).(map[string]interface{})
+	assert.Equal(t, "localhost:6379", redacted["redisAddress"])
+	assert.Equal(t, constants.RedactedValue, redacted["redisPassword"])
+}
+
+func TestProperties(t *testing.T) {
+	directory := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "logger.yml"), []byte("level: info\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "database.yml"), []byte(
+		"dialect: mysql\nserver: localhost\nport: 3306\nname: counter\nusername: root\npassword: pass\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "database-prod.yml"), []byte(
+		"server: db.prod\n"), 0644))
+	assert.NoError(t, os.Setenv("APP_DATABASE_PORT", "3307"))
+	defer func() {
+		_ = os.Unsetenv("APP_DATABASE_PORT")
+	}()
+	assert.NoError(t, configs.Init(directory, "prod", "logger", "database"))
+	c, _, err := configs.Load()
+	assert.NoError(t, err)
+	configs.SetConfig(c)
+
+	properties, err := configs.GetProperties()
+	assert.NoError(t, err)
+	assert.Len(t, properties, 2)
+	database := properties["database"]
+	assert.Equal(t, configs.Property{Value: "mysql", Source: "file"}, database["dialect"])
+	assert.Equal(t, configs.Property{Value: "db.prod", Source: "overlay"}, database["server"])
+	assert.Equal(t, configs.Property{Value: 3307, Source: "env"}, database["port"])
+	assert.Equal(t, configs.Property{Value: "[REDACTED]", Source: "file"}, database["password"])
+	assert.Equal(t, configs.Property{Value: "roundRobin", Source: "default"}, database["replicaStrategy"])
+	assert.Equal(t, configs.Property{Value: 0, Source: "default"}, database["maxOpenConnections"])
+	assert.Equal(t, configs.Property{Value: "", Source: "default"}, database["tls.mode"])
+	assert.Equal(t, configs.Property{Value: "info", Source: "file"}, properties["logger"]["level"])
+
+	// the values are the ones loaded, not the ones changed in the files since
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "logger.yml"), []byte("level: loud\n"), 0644))
+	properties, err = configs.GetProperties()
+	assert.NoError(t, err)
+	assert.Equal(t, configs.Property{Value: "info", Source: "file"}, properties["logger"]["level"])
+}
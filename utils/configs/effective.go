@@ -0,0 +1,157 @@
+package configs
+
+import (
+	"github.com/sinhashubham95/go-example-project/constants"
+	"gopkg.in/yaml.v2"
+	"strings"
+)
+
+// GetEffective is used to get the effective config, the config with its overlay merged into it and the keys
+// overridden by the environment variables set
+// It is read from the files as they are now, keeping the case of the keys, and the secrets are in it as they are.
+func GetEffective(config string) (map[string]interface{}, error) {
+	mu.RLock()
+	dir := directory
+	overlay, hasOverlay := configOverlays[config]
+	o := append([]Override{}, overrides...)
+	mu.RUnlock()
+
+	base, err := readFile(dir, config)
+	if err != nil {
+		return nil, err
+	}
+	effective := copyMap(base)
+	if hasOverlay {
+		value, err := readFile(dir, overlay)
+		if err != nil {
+			return nil, err
+		}
+		effective = merge(effective, value).(map[string]interface{})
+	}
+	for _, override := range o {
+		if override.Config == config {
+			set(effective, strings.Split(override.Key, "."), override.Value)
+		}
+	}
+	return effective, nil
+}
+
+// GetConfigNames is used to get the names of the configs initialised
+func GetConfigNames() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]string{}, configNamesLoaded...)
+}
+
+// Property is the effective value of a leaf key of a config, along with where it comes from
+type Property struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// GetProperties is used to get the effective properties of the typed configs in use, keyed by the config names and
+// the leaf keys, with the secrets redacted
+// The values are the ones loaded last, so a change rejected by a reload is not shown, and the sources are the ones
+// recorded when they were loaded. The keys set nowhere have the values they are defaulted to.
+func GetProperties() (map[string]map[string]Property, error) {
+	c := GetConfig()
+	typed, err := getTypedLeaves(c)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]Property, len(c.sources))
+	for name, sources := range c.sources {
+		properties := make(map[string]Property, len(typed[name]))
+		for key, value := range typed[name] {
+			source, ok := sources[strings.ToLower(key)]
+			if !ok {
+				source = constants.DefaultConfigSource
+			}
+			properties[key] = Property{Value: RedactValue(key, value), Source: source}
+		}
+		result[name] = properties
+	}
+	return result, nil
+}
+
+// getSources is used to get where the values of the leaf keys of the effective config come from, keyed by the keys in
+// lower case, or nothing when the config is not initialised
+func getSources(config string, effective map[string]interface{}) (map[string]string, error) {
+	mu.RLock()
+	dir := directory
+	initialised := false
+	for _, name := range configNamesLoaded {
+		initialised = initialised || name == config
+	}
+	overlayName, hasOverlay := configOverlays[config]
+	o := append([]Override{}, overrides...)
+	mu.RUnlock()
+	if !initialised {
+		return nil, nil
+	}
+
+	overlay := make(map[string]interface{})
+	if hasOverlay {
+		var err error
+		if overlay, err = readFlat(dir, overlayName); err != nil {
+			return nil, err
+		}
+	}
+	leaves := make(map[string]interface{})
+	getLeaves("", effective, leaves)
+	sources := make(map[string]string, len(leaves))
+	for key := range leaves {
+		sources[strings.ToLower(key)] = getSource(config, key, overlay, o)
+	}
+	return sources, nil
+}
+
+// getSource is used to get where the value of the key of the config comes from
+func getSource(config, key string, overlay map[string]interface{}, o []Override) string {
+	for _, override := range o {
+		if override.Config == config && strings.EqualFold(override.Key, key) {
+			return constants.EnvConfigSource
+		}
+	}
+	if _, ok := overlay[strings.ToLower(key)]; ok {
+		return constants.OverlayConfigSource
+	}
+	return constants.FileConfigSource
+}
+
+// getTypedLeaves is used to get the leaf keys of the typed configs along with their values, keyed by the config names
+func getTypedLeaves(c *Config) (map[string]map[string]interface{}, error) {
+	result := make(map[string]map[string]interface{})
+	for name, section := range map[string]interface{}{
+		constants.LoggerConfig:      c.Logger,
+		constants.ApplicationConfig: c.Application,
+		constants.DatabaseConfig:    c.Database,
+	} {
+		data, err := yaml.Marshal(section)
+		if err != nil {
+			return nil, err
+		}
+		var value map[interface{}]interface{}
+		if err = yaml.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		result[name] = make(map[string]interface{})
+		getLeaves("", value, result[name])
+	}
+	return result, nil
+}
+
+// getLeaves is used to get the leaf keys of the value, as they are named in the files, along with their values
+func getLeaves(prefix string, value interface{}, leaves map[string]interface{}) {
+	for k, v := range copyMap(value) {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if isMap(v) && len(copyMap(v)) > 0 {
+			getLeaves(key, v, leaves)
+			continue
+		}
+		leaves[key] = v
+	}
+}
@@ -0,0 +1,10 @@
+package configs
+
+import configs "github.com/angel-one/go-config-client"
+
+// SetClient replaces the config client for the tests, returning the one replaced
+func SetClient(c configs.Client) configs.Client {
+	previous := client
+	client = c
+	return previous
+}
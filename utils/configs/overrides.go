@@ -0,0 +1,221 @@
+package configs
+
+import (
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Override is a config key overridden by an environment variable
+type Override struct {
+	Config string      `json:"config"`
+	Key    string      `json:"key"`
+	Env    string      `json:"env"`
+	Value  interface{} `json:"value"`
+}
+
+// GetEnvName is used to get the environment variable overriding the key of the config
+// It is the config and the key, in upper case with everything but the letters and the digits as underscores,
+// prefixed with APP_, like APP_DATABASE_MAXOPENCONNECTIONS for maxOpenConnections of the database config.
+func GetEnvName(config, key string) string {
+	return constants.ConfigEnvPrefix + normalise(config) + "_" + normalise(key)
+}
+
+// GetOverrides is used to get the config keys overridden by the environment variables, sorted by their names
+func GetOverrides() []Override {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Override{}, overrides...)
+}
+
+// loadOverrides is used to get the overrides of the configs from the environment variables
+// A variable is matched against the keys in the config files first, and otherwise taken to be the key with its
+// underscores as dots. The values are converted to the type of the ones they override, the lists being comma
+// separated, and to the bool or the number they read as for the keys not in the files or the overlays.
+func loadOverrides(dir string, configNames []string, overlays map[string]string) ([]Override, error) {
+	// the longer names are matched first, for a config named after the prefix of another
+	names := append([]string{}, configNames...)
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	values := make(map[string]map[string]interface{}, len(names))
+	for _, name := range names {
+		flat, err := readFlat(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if overlay, ok := overlays[name]; ok {
+			o, err := readFlat(dir, overlay)
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range o {
+				flat[key] = value
+			}
+		}
+		values[name] = flat
+	}
+
+	var result []Override
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], constants.ConfigEnvPrefix) {
+			continue
+		}
+		config, key, ok, err := matchEnv(parts[0], names, values)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		value, err := coerce(parts[1], values[config][key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s for %s of the %s config: %w", parts[0], key, config, err)
+		}
+		result = append(result, Override{Config: config, Key: key, Env: parts[0], Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Env < result[j].Env
+	})
+	return result, nil
+}
+
+// matchEnv is used to get the config and the key overridden by the environment variable
+// A variable matching more than one key of the config is rejected, as nothing tells which of them it overrides.
+func matchEnv(env string, names []string, values map[string]map[string]interface{}) (string, string, bool, error) {
+	for _, name := range names {
+		prefix := constants.ConfigEnvPrefix + normalise(name) + "_"
+		if !strings.HasPrefix(env, prefix) || len(env) == len(prefix) {
+			continue
+		}
+		var keys []string
+		for key := range values[name] {
+			if GetEnvName(name, key) == env {
+				keys = append(keys, key)
+			}
+		}
+		switch len(keys) {
+		case 0:
+			return name, strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(env, prefix), "_", ".")), true, nil
+		case 1:
+			return name, keys[0], true, nil
+		}
+		sort.Strings(keys)
+		return "", "", false, fmt.Errorf("ambiguous %s matching the keys %s of the %s config", env,
+			strings.Join(keys, ", "), name)
+	}
+	return "", "", false, nil
+}
+
+// readFlat is used to read the leaf keys of the config file, in lower case, along with their values
+func readFlat(dir, name string) (map[string]interface{}, error) {
+	flat := make(map[string]interface{})
+	value, err := readFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	flatten("", value, flat)
+	return flat, nil
+}
+
+// readFile is used to read the config file, there being nothing in it when it does not exist
+func readFile(dir, name string) (map[interface{}]interface{}, error) {
+	path, ok := getPath(dir, name)
+	if !ok {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var value map[interface{}]interface{}
+	if err = yaml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("error decoding the %s config: %w", name, err)
+	}
+	return value, nil
+}
+
+func flatten(prefix string, value map[interface{}]interface{}, flat map[string]interface{}) {
+	for k, v := range value {
+		key := strings.ToLower(fmt.Sprint(k))
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if m, ok := v.(map[interface{}]interface{}); ok && len(m) > 0 {
+			flatten(key, m, flat)
+			continue
+		}
+		flat[key] = v
+	}
+}
+
+// coerce is used to convert the value to the type of the one it overrides
+func coerce(raw string, current interface{}) (interface{}, error) {
+	switch c := current.(type) {
+	case bool:
+		return strconv.ParseBool(raw)
+	case int, int64:
+		i, err := strconv.ParseInt(raw, 10, 64)
+		return int(i), err
+	case float64:
+		return strconv.ParseFloat(raw, 64)
+	case []interface{}:
+		list := make([]interface{}, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			var element interface{}
+			if len(c) > 0 {
+				element = c[0]
+			}
+			value, err := coerce(item, element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case map[interface{}]interface{}:
+		return nil, fmt.Errorf("a map cannot be overridden, only the keys in it")
+	case nil:
+		return coerceUnknown(raw), nil
+	default:
+		return raw, nil
+	}
+}
+
+// coerceUnknown is used to convert the value of a key in none of the files to the bool or the number it reads as, for
+// the ones read untyped, like the upstreams, to get it as such
+// The value is kept as it is when converting it back would not give it as it was, like 007, for it to stay a string.
+func coerceUnknown(raw string) interface{} {
+	if b, err := strconv.ParseBool(raw); err == nil && strconv.FormatBool(b) == raw {
+		return b
+	}
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil && strconv.FormatInt(i, 10) == raw {
+		return int(i)
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == raw {
+		return f
+	}
+	return raw
+}
+
+func normalise(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,69 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"strings"
+)
+
+// secretSuffixes are the endings of the keys holding secrets, matched against the last segment of the key
+var secretSuffixes = []string{"password", "secret", "token", "apikey", "credential", "credentials"}
+
+// IsSecret reports whether the value of the key is a secret, kept out of the logs and the actuator
+// The last segment of the key is matched regardless of its case, so both database.password and redisPassword
+// are secrets, and so is the key of an api key auth, while tokenUrl and keyFile are not.
+func IsSecret(key string) bool {
+	parts := strings.Split(strings.ToLower(key), ".")
+	last := parts[len(parts)-1]
+	if last == "key" {
+		return true
+	}
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(last, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValue is used to get the value of the key safe to be shown, redacted when the key is a secret
+// The env: and file: references only say where the secret is kept, so they are shown as they are, unlike the enc:
+// ones. The empty values are left as they are, for them to be told apart from the ones set.
+func RedactValue(key string, value interface{}) interface{} {
+	if !IsSecret(key) || value == nil {
+		return value
+	}
+	s := fmt.Sprint(value)
+	if s == "" || strings.HasPrefix(s, constants.EnvSecretPrefix) || strings.HasPrefix(s, constants.FileSecretPrefix) {
+		return value
+	}
+	return constants.RedactedValue
+}
+
+// Redact is used to get a copy of the config with the values of the secret keys redacted
+func Redact(config map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		if isMap(value) {
+			redacted[key] = Redact(copyMap(value))
+			continue
+		}
+		redacted[key] = RedactValue(key, value)
+	}
+	return redacted
+}
+
+// RedactStruct is used to get the value as it is encoded in json with the values of the secret fields redacted,
+// for the configurations to be logged
+func RedactStruct(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return constants.RedactedValue
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return constants.RedactedValue
+	}
+	return Redact(m)
+}
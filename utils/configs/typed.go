@@ -0,0 +1,452 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	goUtilsConstants "github.com/angel-one/go-utils/constants"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Config is the typed configuration of the application, out of the logger, application and database configs
+type Config struct {
+	Logger      LoggerConfig      `yaml:"-"`
+	Application ApplicationConfig `yaml:"-"`
+	Database    DatabaseConfig    `yaml:"-"`
+	// sources are where the values of the configs initialised come from, keyed by the config names and the leaf keys
+	// in lower case
+	sources map[string]map[string]string
+}
+
+// LoggerConfig is the typed logger config
+type LoggerConfig struct {
+	Level string `yaml:"level"`
+}
+
+// ApplicationConfig is the typed application config
+// The http entries are the upstream requests, configured by the http client as they are.
+type ApplicationConfig struct {
+	Counter CounterConfig                     `yaml:"counter"`
+	Secrets SecretsConfig                     `yaml:"secrets"`
+	Outbox  OutboxConfig                      `yaml:"outbox"`
+	Admin   AdminConfig                       `yaml:"admin"`
+	HTTP    map[string]map[string]interface{} `yaml:"http"`
+}
+
+// CounterConfig is the config of the counters, the query timeout being zero for the queries to not time out
+type CounterConfig struct {
+	QueryTimeoutInMillis int                `yaml:"queryTimeoutInMillis"`
+	Cache                CounterCacheConfig `yaml:"cache"`
+}
+
+// CounterCacheConfig is the config of the cache of the counts
+type CounterCacheConfig struct {
+	Backend     string      `yaml:"backend"`
+	Size        int         `yaml:"size"`
+	TTLInMillis int         `yaml:"ttlInMillis"`
+	Redis       RedisConfig `yaml:"redis"`
+}
+
+// RedisConfig is the config of the redis cache backend
+type RedisConfig struct {
+	Address         string `yaml:"address"`
+	Password        string `yaml:"password"`
+	Database        int    `yaml:"database"`
+	TimeoutInMillis int    `yaml:"timeoutInMillis"`
+	PoolSize        int    `yaml:"poolSize"`
+}
+
+// SecretsConfig is the config of the secret references
+type SecretsConfig struct {
+	KeyFile string `yaml:"keyFile"`
+}
+
+// AdminConfig is the config of the admin api, the token being the secret reference to the bearer token it requires
+type AdminConfig struct {
+	Token string `yaml:"token"`
+}
+
+// OutboxConfig is the config of the outbox and its relay
+type OutboxConfig struct {
+	Enabled              bool   `yaml:"enabled"`
+	Sink                 string `yaml:"sink"`
+	FilePath             string `yaml:"filePath"`
+	PollIntervalInMillis int    `yaml:"pollIntervalInMillis"`
+	BatchSize            int    `yaml:"batchSize"`
+	BackoffInMillis      int    `yaml:"backoffInMillis"`
+	MaxBackoffInMillis   int    `yaml:"maxBackoffInMillis"`
+}
+
+// DatabaseConfig is the typed database config
+type DatabaseConfig struct {
+	Dialect                                 string            `yaml:"dialect"`
+	Driver                                  string            `yaml:"driver"`
+	Server                                  string            `yaml:"server"`
+	Port                                    int               `yaml:"port"`
+	Name                                    string            `yaml:"name"`
+	Username                                string            `yaml:"username"`
+	Password                                string            `yaml:"password"`
+	MaxOpenConnections                      int               `yaml:"maxOpenConnections"`
+	MaxIdleConnections                      int               `yaml:"maxIdleConnections"`
+	ConnectionMaxLifetimeInSeconds          int               `yaml:"connectionMaxLifetimeInSeconds"`
+	ConnectionMaxIdleTimeInSeconds          int               `yaml:"connectionMaxIdleTimeInSeconds"`
+	TransactionMaxRetries                   int               `yaml:"transactionMaxRetries"`
+	TransactionBackoffInMillis              int               `yaml:"transactionBackoffInMillis"`
+	Replicas                                []string          `yaml:"replicas"`
+	ReplicaStrategy                         string            `yaml:"replicaStrategy"`
+	ReplicaHealthCheckIntervalInMillis      int               `yaml:"replicaHealthCheckIntervalInMillis"`
+	StartupTimeoutInMillis                  int               `yaml:"startupTimeoutInMillis"`
+	StartupBackoffInMillis                  int               `yaml:"startupBackoffInMillis"`
+	HealthCheckIntervalInMillis             int               `yaml:"healthCheckIntervalInMillis"`
+	SlowQueryThresholdInMillis              int               `yaml:"slowQueryThresholdInMillis"`
+	CredentialRotationCheckIntervalInMillis int               `yaml:"credentialRotationCheckIntervalInMillis"`
+	TLS                                     DatabaseTLSConfig `yaml:"tls"`
+	DialTimeoutInMillis                     int               `yaml:"dialTimeoutInMillis"`
+	ReadTimeoutInMillis                     int               `yaml:"readTimeoutInMillis"`
+	WriteTimeoutInMillis                    int               `yaml:"writeTimeoutInMillis"`
+	ParseTime                               bool              `yaml:"parseTime"`
+	Charset                                 string            `yaml:"charset"`
+	Collation                               string            `yaml:"collation"`
+	Params                                  map[string]string `yaml:"params"`
+}
+
+// DatabaseTLSConfig is the config of the tls connections to the database
+type DatabaseTLSConfig struct {
+	Mode       string `yaml:"mode"`
+	CAFile     string `yaml:"caFile"`
+	CertFile   string `yaml:"certFile"`
+	KeyFile    string `yaml:"keyFile"`
+	ServerName string `yaml:"serverName"`
+}
+
+// ValidationError is all the problems found loading the configs, every one of them being the key and what is wrong
+type ValidationError struct {
+	Problems []string
+}
+
+// decoder collects the unknown keys and the problems found decoding the configs
+type decoder struct {
+	warnings []string
+	problems []string
+}
+
+// validator collects the problems found validating a config
+type validator struct {
+	problems []string
+}
+
+var current atomic.Value
+
+// Load is used to load the typed configs from the configs initialised, validating them
+// The keys of the configs are matched regardless of their case, and the keys matching none are returned as
+// warnings rather than failing the load. The error is a ValidationError with all the problems found.
+func Load() (*Config, []string, error) {
+	c := defaultConfig()
+	c.sources = make(map[string]map[string]string)
+	d := &decoder{}
+	for _, part := range []struct {
+		name  string
+		value interface{}
+	}{
+		{name: constants.LoggerConfig, value: &c.Logger},
+		{name: constants.ApplicationConfig, value: &c.Application},
+		{name: constants.DatabaseConfig, value: &c.Database},
+	} {
+		effective, err := GetEffective(part.name)
+		if err != nil {
+			return nil, nil, err
+		}
+		sources, err := getSources(part.name, effective)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sources != nil {
+			c.sources[part.name] = sources
+		}
+		value, _ := d.canonicalise(effective, reflect.TypeOf(part.value).Elem(), part.name)
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = yaml.Unmarshal(data, part.value); err != nil {
+			return nil, nil, err
+		}
+	}
+	sort.Strings(d.warnings)
+	sort.Strings(d.problems)
+	var validationErr *ValidationError
+	if err := c.Validate(); errors.As(err, &validationErr) {
+		d.problems = append(d.problems, validationErr.Problems...)
+	}
+	if len(d.problems) > 0 {
+		return c, d.warnings, &ValidationError{Problems: d.problems}
+	}
+	return c, d.warnings, nil
+}
+
+// GetRequests is used to get the http entries as the configs of the upstream requests, keyed by their names
+func (c ApplicationConfig) GetRequests() map[string]interface{} {
+	requests := make(map[string]interface{}, len(c.HTTP))
+	for name, request := range c.HTTP {
+		requests[name] = request
+	}
+	return requests
+}
+
+// GetConfig is used to get the typed configs set last, the defaults until they are set
+func GetConfig() *Config {
+	if c, ok := current.Load().(*Config); ok && c != nil {
+		return c
+	}
+	return defaultConfig()
+}
+
+// SetConfig is used to set the typed configs, once they are loaded
+func SetConfig(c *Config) {
+	current.Store(c)
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Application: ApplicationConfig{
+			Counter: CounterConfig{Cache: CounterCacheConfig{Backend: constants.NoCacheBackend}},
+		},
+		Database: DatabaseConfig{ReplicaStrategy: constants.RoundRobinReplicaStrategy},
+	}
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configs:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate is used to validate the configs, all the problems found being in the error
+func (c *Config) Validate() error {
+	v := &validator{}
+	v.merge(constants.LoggerConfig, c.Logger.Validate())
+	v.merge(constants.ApplicationConfig, c.Application.Validate())
+	v.merge(constants.DatabaseConfig, c.Database.Validate())
+	return v.err()
+}
+
+// Validate is used to validate the logger config
+func (c LoggerConfig) Validate() error {
+	v := &validator{}
+	v.oneOf("level", c.Level, goUtilsConstants.TraceLevel, goUtilsConstants.DebugLevel, goUtilsConstants.InfoLevel,
+		goUtilsConstants.WarnLevel, goUtilsConstants.ErrorLevel, goUtilsConstants.FatalLevel,
+		goUtilsConstants.PanicLevel)
+	return v.err()
+}
+
+// Validate is used to validate the application config
+func (c ApplicationConfig) Validate() error {
+	v := &validator{}
+	v.merge("counter", c.Counter.Validate())
+	v.merge("outbox", c.Outbox.Validate())
+	if c.Outbox.Enabled && c.Outbox.Sink == constants.HTTPOutboxSink {
+		_, ok := c.HTTP[constants.OutboxRequestName]
+		v.check(ok, "http."+constants.OutboxRequestName, "is required by the http outbox sink")
+	}
+	names := make([]string, 0, len(c.HTTP))
+	for name := range c.HTTP {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		validateRequest(v, "http."+name, c.HTTP[name])
+	}
+	return v.err()
+}
+
+// Validate is used to validate the counter config
+func (c CounterConfig) Validate() error {
+	v := &validator{}
+	v.check(c.QueryTimeoutInMillis >= 0, "queryTimeoutInMillis", "cannot be negative")
+	v.oneOf("cache.backend", c.Cache.Backend, constants.NoCacheBackend, constants.LRUCacheBackend,
+		constants.RedisCacheBackend)
+	v.check(c.Cache.TTLInMillis >= 0, "cache.ttlInMillis", "cannot be negative")
+	switch c.Cache.Backend {
+	case constants.LRUCacheBackend:
+		v.check(c.Cache.Size > 0, "cache.size", "should be positive for the lru cache")
+	case constants.RedisCacheBackend:
+		v.check(c.Cache.Redis.Address != "", "cache.redis.address", "is required for the redis cache")
+		v.check(c.Cache.Redis.Database >= 0, "cache.redis.database", "cannot be negative")
+		v.check(c.Cache.Redis.TimeoutInMillis >= 0, "cache.redis.timeoutInMillis", "cannot be negative")
+		v.check(c.Cache.Redis.PoolSize >= 0, "cache.redis.poolSize", "cannot be negative")
+	}
+	return v.err()
+}
+
+// Validate is used to validate the outbox config, which is not validated further when it is disabled
+func (c OutboxConfig) Validate() error {
+	v := &validator{}
+	if !c.Enabled {
+		return nil
+	}
+	v.oneOf("sink", c.Sink, constants.HTTPOutboxSink, constants.FileOutboxSink)
+	v.check(c.PollIntervalInMillis > 0, "pollIntervalInMillis", "should be positive")
+	v.check(c.BatchSize >= 0, "batchSize", "cannot be negative")
+	v.check(c.BackoffInMillis >= 0, "backoffInMillis", "cannot be negative")
+	v.check(c.MaxBackoffInMillis == 0 || c.MaxBackoffInMillis >= c.BackoffInMillis, "maxBackoffInMillis",
+		"cannot be less than the backoff")
+	return v.err()
+}
+
+// Validate is used to validate the database config
+func (c DatabaseConfig) Validate() error {
+	v := &validator{}
+	v.oneOf("dialect", c.Dialect, constants.MySQLDialect, constants.PostgresDialect, constants.SQLiteDialect)
+	v.check(c.Name != "", "name", "is required")
+	if c.Dialect != constants.SQLiteDialect {
+		v.check(c.Server != "", "server", "is required")
+		v.check(c.Port > 0 && c.Port <= 65535, "port", "should be between 1 and 65535")
+	}
+	v.check(c.MaxOpenConnections >= 0, "maxOpenConnections", "cannot be negative")
+	v.check(c.MaxIdleConnections >= 0, "maxIdleConnections", "cannot be negative")
+	v.check(c.MaxOpenConnections == 0 || c.MaxIdleConnections <= c.MaxOpenConnections, "maxIdleConnections",
+		"cannot be more than the max open connections")
+	v.check(c.TransactionMaxRetries >= 0, "transactionMaxRetries", "cannot be negative")
+	v.oneOf("replicaStrategy", c.ReplicaStrategy, constants.RoundRobinReplicaStrategy,
+		constants.LeastLatencyReplicaStrategy)
+	if c.TLS.Mode != "" {
+		v.oneOf("tls.mode", c.TLS.Mode, constants.DisabledTLSMode, constants.PreferredTLSMode,
+			constants.SkipVerifyTLSMode, constants.VerifyTLSMode)
+	}
+	for key, value := range map[string]int{
+		"connectionMaxLifetimeInSeconds":          c.ConnectionMaxLifetimeInSeconds,
+		"connectionMaxIdleTimeInSeconds":          c.ConnectionMaxIdleTimeInSeconds,
+		"transactionBackoffInMillis":              c.TransactionBackoffInMillis,
+		"replicaHealthCheckIntervalInMillis":      c.ReplicaHealthCheckIntervalInMillis,
+		"startupTimeoutInMillis":                  c.StartupTimeoutInMillis,
+		"startupBackoffInMillis":                  c.StartupBackoffInMillis,
+		"healthCheckIntervalInMillis":             c.HealthCheckIntervalInMillis,
+		"slowQueryThresholdInMillis":              c.SlowQueryThresholdInMillis,
+		"credentialRotationCheckIntervalInMillis": c.CredentialRotationCheckIntervalInMillis,
+		"dialTimeoutInMillis":                     c.DialTimeoutInMillis,
+		"readTimeoutInMillis":                     c.ReadTimeoutInMillis,
+		"writeTimeoutInMillis":                    c.WriteTimeoutInMillis,
+	} {
+		v.check(value >= 0, key, "cannot be negative")
+	}
+	sort.Strings(v.problems)
+	return v.err()
+}
+
+// validateRequest is used to validate the upstream request, the rest of it being validated by the http client
+func validateRequest(v *validator, key string, request map[string]interface{}) {
+	var method, u string
+	for k, value := range request {
+		switch {
+		case strings.EqualFold(k, constants.HTTPMethodConfigKey):
+			method = strings.ToUpper(fmt.Sprint(value))
+		case strings.EqualFold(k, constants.HTTPURLConfigKey):
+			u = fmt.Sprint(value)
+		}
+	}
+	v.oneOf(key+"."+constants.HTTPMethodConfigKey, method, "", http.MethodGet, http.MethodHead, http.MethodPost,
+		http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions)
+	parsed, err := url.Parse(u)
+	v.check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "",
+		key+"."+constants.HTTPURLConfigKey, "should be an absolute http or https url")
+}
+
+func (v *validator) check(ok bool, key, problem string) {
+	if !ok {
+		v.problems = append(v.problems, key+": "+problem)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	if value == "" {
+		v.problems = append(v.problems, key+": is required")
+		return
+	}
+	v.problems = append(v.problems, fmt.Sprintf("%s: %q should be one of %s", key, value,
+		strings.Join(allowed, ", ")))
+}
+
+// merge is used to add the problems of the part of the config, prefixed by its key
+func (v *validator) merge(key string, err error) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		for _, problem := range validationErr.Problems {
+			v.problems = append(v.problems, key+"."+problem)
+		}
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// canonicalise is used to rename the keys of the value to the yaml names of the fields of the type, matching them
+// regardless of their case, collecting the keys matching none of them as warnings
+// The values that cannot be decoded into their fields are left out of the result, collected as problems.
+func (d *decoder) canonicalise(value interface{}, t reflect.Type, key string) (interface{}, bool) {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		if value == nil {
+			return nil, true
+		}
+		if !isMap(value) {
+			d.problems = append(d.problems, key+": should be a map")
+			return nil, false
+		}
+		result := make(map[string]interface{})
+		for k, v := range copyMap(value) {
+			name, elem := k, t
+			if t.Kind() == reflect.Struct {
+				f, ok := getField(t, k)
+				if !ok {
+					d.warnings = append(d.warnings, key+"."+k)
+					continue
+				}
+				name, elem = f.name, f.t
+			} else {
+				elem = t.Elem()
+			}
+			if c, ok := d.canonicalise(v, elem, key+"."+name); ok {
+				result[name] = c
+			}
+		}
+		return result, true
+	default:
+		data, err := yaml.Marshal(value)
+		if err == nil {
+			err = yaml.Unmarshal(data, reflect.New(t).Interface())
+		}
+		if err != nil {
+			d.problems = append(d.problems, fmt.Sprintf("%s: %v cannot be decoded as %s", key, value, t))
+			return nil, false
+		}
+		return value, true
+	}
+}
+
+type field struct {
+	name string
+	t    reflect.Type
+}
+
+func getField(t reflect.Type, key string) (field, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" && strings.EqualFold(name, key) {
+			return field{name: name, t: t.Field(i).Type}, true
+		}
+	}
+	return field{}, false
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/utils/secrets"
+)
+
+var credentials *secrets.Watcher
+
+// resolveCredentials is used to get the configuration with the credential references replaced by the secrets
+func resolveCredentials(config Config) (Config, error) {
+	var err error
+	config.Username, err = secrets.Resolve(config.Username)
+	if err != nil {
+		return config, err
+	}
+	config.Password, err = secrets.Resolve(config.Password)
+	if err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// watchCredentials is used to re-dial the primary and the replicas whenever the secret files change
+func watchCredentials(d Dialect, config Config) *secrets.Watcher {
+	files := secrets.Files(config.Username, config.Password)
+	if config.CredentialRotationCheckInterval <= 0 || len(files) == 0 {
+		return nil
+	}
+	return secrets.NewWatcher(config.CredentialRotationCheckInterval, func() {
+		rotateCredentials(context.Background(), d, config)
+	}, files...)
+}
+
+func rotateCredentials(ctx context.Context, d Dialect, config Config) {
+	resolved, err := resolveCredentials(config)
+	if err != nil {
+		log.Error(ctx).Err(err).Msg("error resolving rotated database credentials, keeping the current ones")
+		return
+	}
+	if err = connector.redial(d.DSN(resolved)); err != nil {
+		log.Error(ctx).Err(err).Msg("error re-dialing database with rotated credentials")
+	}
+	for _, r := range replicas.replicas {
+		replicaConfig, err := getReplicaConfig(resolved, r.address)
+		if err == nil {
+			err = r.connector.redial(d.DSN(replicaConfig))
+		}
+		if err != nil {
+			log.Error(ctx).Err(err).Msgf("error re-dialing replica %s with rotated credentials", r.address)
+		}
+	}
+	log.Info(ctx).Msg("database credentials rotated, connections are re-dialed as they are released")
+}
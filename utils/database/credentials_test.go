@@ -0,0 +1,50 @@
+package database_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialRotation(t *testing.T) {
+	registerRouting()
+	path := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("first"), 0600))
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{
+		Driver:                          "routing",
+		Server:                          "primary",
+		Port:                            3306,
+		Username:                        "counter",
+		Password:                        "file:" + path,
+		MaxIdleConnections:              2,
+		CredentialRotationCheckInterval: 5 * time.Millisecond,
+	}))
+	defer func() {
+		assert.NoError(t, database.Close())
+	}()
+
+	ctx := context.Background()
+	assert.NoError(t, database.InTransaction(ctx, nil, exec))
+	assert.Contains(t, routing.last(), "counter:first@")
+
+	// the idle connection dialed with the previous password is not reused
+	assert.NoError(t, ioutil.WriteFile(path, []byte("second"), 0600))
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, database.InTransaction(ctx, nil, exec))
+		return strings.Contains(routing.last(), "counter:second@")
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestUnresolvedCredentials(t *testing.T) {
+	err := database.InitDatabase(context.Background(), database.Config{
+		Driver:   "routing",
+		Password: "env:DATABASE_TEST_MISSING_PASSWORD",
+	})
+	assert.Error(t, err)
+}
@@ -6,50 +6,125 @@ import (
 	"fmt"
 	"github.com/angel-one/go-utils/log"
 	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
 	"time"
 )
 
 type Config struct {
+	Dialect               string        `json:"dialect"`
+	Driver                string        `json:"driver"`
 	Server                string        `json:"server"`
 	Port                  int           `json:"port"`
 	Name                  string        `json:"name"`
-	Username              string        `json:"-"`
-	Password              string        `json:"-"`
+	Username              string        `json:"username"`
+	Password              string        `json:"password"`
 	MaxOpenConnections    int           `json:"maxOpenConnections"`
 	MaxIdleConnections    int           `json:"maxIdleConnections"`
 	ConnectionMaxLifetime time.Duration `json:"connectionMaxLifetime"`
 	ConnectionMaxIdleTime time.Duration `json:"connectionMaxIdleTime"`
+	TransactionRetry      RetryPolicy   `json:"transactionRetry"`
+	// Replicas are the host:port addresses of the read replicas sharing the credentials of the primary
+	Replicas                   []string      `json:"replicas"`
+	ReplicaStrategy            string        `json:"replicaStrategy"`
+	ReplicaHealthCheckInterval time.Duration `json:"replicaHealthCheckInterval"`
+	// StartupTimeout is how long the startup keeps retrying, with StartupBackoff doubling after every attempt
+	StartupTimeout      time.Duration `json:"startupTimeout"`
+	StartupBackoff      time.Duration `json:"startupBackoff"`
+	HealthCheckInterval time.Duration `json:"healthCheckInterval"`
+	SlowQueryThreshold  time.Duration `json:"slowQueryThreshold"`
+	// CredentialRotationCheckInterval is how often the secret files behind the credentials are checked for changes
+	CredentialRotationCheckInterval time.Duration `json:"credentialRotationCheckInterval"`
+	// the connection options below are only supported by the mysql dialect
+	TLS          TLSConfig         `json:"tls"`
+	DialTimeout  time.Duration     `json:"dialTimeout"`
+	ReadTimeout  time.Duration     `json:"readTimeout"`
+	WriteTimeout time.Duration     `json:"writeTimeout"`
+	ParseTime    bool              `json:"parseTime"`
+	Charset      string            `json:"charset"`
+	Collation    string            `json:"collation"`
+	Params       map[string]string `json:"params"`
 }
 
-var db *sql.DB
+var (
+	db        *sql.DB
+	connector *instrumentedConnector
+)
 
 func InitDatabase(ctx context.Context, config Config) error {
-	log.Info(ctx).Interface(constants.DatabaseConfigKey, config).Msg("initializing database")
-	var err error
+	d, err := GetDialect(config.Dialect)
+	if err != nil {
+		return err
+	}
+	driver := config.Driver
+	if driver == "" {
+		driver = d.DriverName()
+	}
 
-	// open the database
-	db, err = sql.Open(
-		constants.MySQLDriverName,
-		fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", config.Username, config.Password,
-			config.Server, config.Port, config.Name),
-	)
+	// the credentials are references to where the secrets are kept
+	resolved, err := resolveCredentials(config)
 	if err != nil {
 		return err
 	}
+	if err = d.Configure(resolved); err != nil {
+		return fmt.Errorf("invalid database configuration: %w", err)
+	}
 
-	// try to ping
-	err = db.Ping()
+	// open the database
+	db, connector, err = open(driver, d.DSN(resolved))
 	if err != nil {
 		return err
 	}
 
 	// now set the configurations
+	configurePool(db, config)
+	dialect = d
+	retryPolicy = config.TransactionRetry
+	slowQueryThreshold = config.SlowQueryThreshold
+
+	// try to ping, an unreachable database is not fatal as the monitor keeps checking for it to come back
+	err = connect(ctx, db, config.StartupTimeout, config.StartupBackoff)
+	if err != nil {
+		log.Error(ctx).Err(err).Msg("database unreachable, starting in degraded mode")
+	}
+	health = startMonitor(db, err == nil, config.HealthCheckInterval)
+
+	// and open the replicas to route the read only transactions to
+	set, err := openReplicas(ctx, d, driver, resolved)
+	if err != nil {
+		health.close()
+		health = &monitor{}
+		_ = db.Close()
+		return err
+	}
+	replicas = set
+	metrics.RegisterGauge(constants.DatabaseReplicasMetric, func() interface{} {
+		return GetReplicaStatuses()
+	})
+	metrics.RegisterGauge(constants.DatabasePoolMetric, func() interface{} {
+		return GetPoolStats()
+	})
+	credentials = watchCredentials(d, config)
+
+	return nil
+}
+
+func configurePool(db *sql.DB, config Config) {
 	db.SetMaxOpenConns(config.MaxOpenConnections)
 	db.SetMaxIdleConns(config.MaxIdleConnections)
 	db.SetConnMaxIdleTime(config.ConnectionMaxIdleTime)
 	db.SetConnMaxLifetime(config.ConnectionMaxLifetime)
+}
 
-	return nil
+// ConfigurePool is used to apply the pool sizes and the connection lifetimes of the configuration to the primary and
+// the replicas, keeping the open connections
+func ConfigurePool(config Config) {
+	if db == nil {
+		return
+	}
+	configurePool(db, config)
+	for _, r := range replicas.replicas {
+		configurePool(r.db, config)
+	}
 }
 
 func Get() *sql.DB {
@@ -57,5 +132,16 @@ func Get() *sql.DB {
 }
 
 func Close() error {
-	return db.Close()
+	if credentials != nil {
+		credentials.Close()
+		credentials = nil
+	}
+	health.close()
+	health = &monitor{}
+	err := replicas.close()
+	replicas = &replicaSet{}
+	if closeErr := db.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
 }
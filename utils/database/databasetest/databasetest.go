@@ -0,0 +1,188 @@
+// Package databasetest is used to stand in for the databases in the tests, with a driver serving the statements run
+// against it with the handlers of the tests
+package databasetest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// Handlers serve the statements run against the Driver, along with the data source they are run against
+type Handlers struct {
+	// Prepare is used to reject the statements before they are run, all of them being accepted when it is unset
+	Prepare func(query string) error
+	// Exec is used to run the statements, each affecting a single row when it is unset
+	Exec func(dsn, query string, args []driver.Value) (driver.Result, error)
+	// Query is used to run the queries, none of them returning any rows when it is unset
+	Query func(dsn, query string, args []driver.Value) (driver.Rows, error)
+	// Ping is used to check the connections, all of them being up when it is unset
+	Ping func(dsn string) error
+}
+
+// Driver is a database driver serving the statements with its handlers, keeping track of the transactions
+type Driver struct {
+	mu         sync.Mutex
+	handlers   Handlers
+	committed  int
+	rolledBack int
+}
+
+type conn struct {
+	driver *Driver
+	dsn    string
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+type tx struct {
+	driver *Driver
+}
+
+// Rows are the rows returned by a query, in the order they are returned
+type Rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+var (
+	mu sync.Mutex
+	// drivers are the drivers registered so far, as registering a name twice panics when the tests are rerun
+	drivers = make(map[string]*Driver)
+)
+
+// Register is used to get the driver registered with the name, serving the statements with the handlers
+// The driver registered with the name already is reused, with its handlers replaced and its counts reset.
+func Register(name string, handlers Handlers) *Driver {
+	mu.Lock()
+	defer mu.Unlock()
+	d, ok := drivers[name]
+	if !ok {
+		d = &Driver{}
+		drivers[name] = d
+		sql.Register(name, d)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers, d.committed, d.rolledBack = handlers, 0, 0
+	return d
+}
+
+// NewRows is used to get the rows of the columns, each of the values being a row
+func NewRows(columns []string, values ...[]driver.Value) *Rows {
+	return &Rows{columns: columns, values: values}
+}
+
+// Open is used to open a connection to the data source
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	return &conn{driver: d, dsn: dsn}, nil
+}
+
+// GetCommitted is used to get the number of transactions committed since the driver was registered
+func (d *Driver) GetCommitted() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.committed
+}
+
+// GetRolledBack is used to get the number of transactions rolled back since the driver was registered
+func (d *Driver) GetRolledBack() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.rolledBack
+}
+
+func (d *Driver) getHandlers() Handlers {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.handlers
+}
+
+func (c *conn) Ping(context.Context) error {
+	if ping := c.driver.getHandlers().Ping; ping != nil {
+		return ping(c.dsn)
+	}
+	return nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	if prepare := c.driver.getHandlers().Prepare; prepare != nil {
+		if err := prepare(query); err != nil {
+			return nil, err
+		}
+	}
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return &tx{driver: c.driver}, nil
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c.Begin()
+}
+
+func (t *tx) Commit() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	t.driver.committed++
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	t.driver.rolledBack++
+	return nil
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	if exec := s.conn.driver.getHandlers().Exec; exec != nil {
+		return exec(s.conn.dsn, s.query, args)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	if query := s.conn.driver.getHandlers().Query; query != nil {
+		return query(s.conn.dsn, s.query, args)
+	}
+	return NewRows(nil), nil
+}
+
+// Columns is used to get the names of the columns of the rows
+func (r *Rows) Columns() []string {
+	return r.columns
+}
+
+// Close is used to close the rows
+func (r *Rows) Close() error {
+	return nil
+}
+
+// Next is used to get the next row into dest
+func (r *Rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,304 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// mysql error numbers for which the whole transaction can safely be retried
+const (
+	mysqlLockDeadlockErrorNumber    = 1213
+	mysqlLockWaitTimeoutErrorNumber = 1205
+)
+
+// postgres error codes for which the whole transaction can safely be retried
+const (
+	postgresSerializationFailureCode = "40001"
+	postgresDeadlockDetectedCode     = "40P01"
+)
+
+// Dialect is the set of database specific behaviour used while building and running queries
+type Dialect interface {
+	// DriverName is the name of the database/sql driver used by default for this dialect
+	DriverName() string
+	// Configure is used to validate the configuration and register anything the data source name refers to,
+	// like the TLS configuration, before the data source name is built
+	Configure(config Config) error
+	// DSN is used to build the data source name from the configuration
+	DSN(config Config) string
+	// Rebind is used to rewrite the ? placeholders in the query to the ones understood by the database
+	Rebind(query string) string
+	// Upsert is used to build an insert statement for the table which on conflict with the key columns
+	// updates the update columns, or does nothing when there are no update columns.
+	// Like every other query, the statement uses ? placeholders and has to be rebound before use.
+	Upsert(table string, keyColumns, columns, updateColumns []string) string
+	// LockForUpdate is used to lock the rows selected by the query until the end of the transaction,
+	// skipping the rows already locked by another transaction
+	LockForUpdate(query string) string
+	// IsRetryableError is used to check whether the error is a deadlock or a serialization failure
+	IsRetryableError(err error) bool
+}
+
+var dialects = map[string]Dialect{
+	constants.MySQLDialect:    mysqlDialect{},
+	constants.PostgresDialect: postgresDialect{},
+	constants.SQLiteDialect:   sqliteDialect{},
+}
+
+var dialect Dialect = mysqlDialect{}
+
+// GetDialect is used to get the dialect registered against the name
+func GetDialect(name string) (Dialect, error) {
+	if name == "" {
+		return mysqlDialect{}, nil
+	}
+	if d, ok := dialects[name]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("invalid database dialect %s provided", name)
+}
+
+// Rebind is used to rewrite the ? placeholders in the query for the configured dialect
+func Rebind(query string) string {
+	return dialect.Rebind(query)
+}
+
+// Upsert is used to build an upsert statement for the configured dialect
+func Upsert(table string, keyColumns, columns, updateColumns []string) string {
+	return dialect.Upsert(table, keyColumns, columns, updateColumns)
+}
+
+// LockForUpdate is used to lock the rows selected by the query for the configured dialect
+func LockForUpdate(query string) string {
+	return dialect.LockForUpdate(query)
+}
+
+// IsRetryableError is used to check whether the error is a deadlock or a serialization failure
+// for the configured dialect
+func IsRetryableError(err error) bool {
+	return dialect.IsRetryableError(err)
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) DriverName() string {
+	return constants.MySQLDriverName
+}
+
+func (d mysqlDialect) Configure(config Config) error {
+	if config.DialTimeout < 0 || config.ReadTimeout < 0 || config.WriteTimeout < 0 {
+		return errors.New("database timeouts cannot be negative")
+	}
+	switch config.TLS.Mode {
+	case "", constants.DisabledTLSMode, constants.PreferredTLSMode, constants.SkipVerifyTLSMode:
+		if config.TLS.hasFiles() {
+			return fmt.Errorf("database TLS files can only be used with the %s mode", constants.VerifyTLSMode)
+		}
+	case constants.VerifyTLSMode:
+		if config.TLS.hasFiles() || config.TLS.ServerName != "" {
+			tlsConfig, err := newTLSConfig(config.TLS)
+			if err != nil {
+				return err
+			}
+			if err = mysql.RegisterTLSConfig(getTLSConfigName(config), tlsConfig); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid database TLS mode %s provided", config.TLS.Mode)
+	}
+	// parsing the data source name back catches the values the driver does not understand
+	_, err := mysql.ParseDSN(d.DSN(config))
+	return err
+}
+
+func (mysqlDialect) DSN(config Config) string {
+	c := mysql.NewConfig()
+	c.User = config.Username
+	c.Passwd = config.Password
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(config.Server, strconv.Itoa(config.Port))
+	c.DBName = config.Name
+	c.Timeout = config.DialTimeout
+	c.ReadTimeout = config.ReadTimeout
+	c.WriteTimeout = config.WriteTimeout
+	c.ParseTime = config.ParseTime
+	if config.Collation != "" {
+		c.Collation = config.Collation
+	}
+	switch config.TLS.Mode {
+	case constants.PreferredTLSMode:
+		c.TLSConfig = "preferred"
+	case constants.SkipVerifyTLSMode:
+		c.TLSConfig = "skip-verify"
+	case constants.VerifyTLSMode:
+		c.TLSConfig = "true"
+		if config.TLS.hasFiles() || config.TLS.ServerName != "" {
+			c.TLSConfig = getTLSConfigName(config)
+		}
+	}
+	if config.Charset != "" || len(config.Params) > 0 {
+		c.Params = make(map[string]string, len(config.Params)+1)
+		for name, value := range config.Params {
+			c.Params[name] = value
+		}
+		if config.Charset != "" {
+			c.Params["charset"] = config.Charset
+		}
+	}
+	return c.FormatDSN()
+}
+
+// getTLSConfigName is used to get the name the TLS config of the host is registered with, the primary and every
+// replica having their own for each to be verified against its own server name
+func getTLSConfigName(config Config) string {
+	return constants.MySQLTLSConfigName + "-" + config.Server + "-" + strconv.Itoa(config.Port)
+}
+
+func (mysqlDialect) Rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) Upsert(table string, keyColumns, columns, updateColumns []string) string {
+	assignments := make([]string, 0, len(updateColumns))
+	for _, column := range updateColumns {
+		assignments = append(assignments, fmt.Sprintf("%s = values(%s)", column, column))
+	}
+	if len(assignments) == 0 {
+		// assigning the key to itself leaves the row untouched and reports no affected rows
+		assignments = append(assignments, fmt.Sprintf("%s = %s", keyColumns[0], keyColumns[0]))
+	}
+	return fmt.Sprintf("%s on duplicate key update %s", insert(table, columns),
+		strings.Join(assignments, ", "))
+}
+
+func (mysqlDialect) LockForUpdate(query string) string {
+	// skip locked needs mysql 8.0 or later
+	return query + " for update skip locked"
+}
+
+func (mysqlDialect) IsRetryableError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlLockDeadlockErrorNumber || mysqlErr.Number == mysqlLockWaitTimeoutErrorNumber
+	}
+	return false
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) DriverName() string {
+	return constants.PostgresDriverName
+}
+
+func (postgresDialect) Configure(Config) error {
+	return nil
+}
+
+func (postgresDialect) DSN(config Config) string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(config.Username, config.Password),
+		Host:   net.JoinHostPort(config.Server, strconv.Itoa(config.Port)),
+		Path:   "/" + config.Name,
+	}
+	return dsn.String()
+}
+
+func (postgresDialect) Rebind(query string) string {
+	var builder strings.Builder
+	builder.Grow(len(query) + 8)
+	position := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			position++
+			builder.WriteString("$" + strconv.Itoa(position))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+func (postgresDialect) Upsert(table string, keyColumns, columns, updateColumns []string) string {
+	return onConflict(table, keyColumns, columns, updateColumns)
+}
+
+func (postgresDialect) LockForUpdate(query string) string {
+	return query + " for update skip locked"
+}
+
+func (postgresDialect) IsRetryableError(err error) bool {
+	// both lib/pq and pgx expose the sql state of the error
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		return state == postgresSerializationFailureCode || state == postgresDeadlockDetectedCode
+	}
+	return false
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) DriverName() string {
+	return constants.SQLiteDriverName
+}
+
+func (sqliteDialect) Configure(Config) error {
+	return nil
+}
+
+func (sqliteDialect) DSN(config Config) string {
+	// for the embedded database the name is the path to the database file
+	return config.Name
+}
+
+func (sqliteDialect) Rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) Upsert(table string, keyColumns, columns, updateColumns []string) string {
+	return onConflict(table, keyColumns, columns, updateColumns)
+}
+
+func (sqliteDialect) LockForUpdate(query string) string {
+	// sqlite has no row locks, the first write locks the whole database and
+	// a transaction which read the rows before that fails as busy and is retried
+	return query
+}
+
+func (sqliteDialect) IsRetryableError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+func insert(table string, columns []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return fmt.Sprintf("insert into %s (%s) values (%s)", table, strings.Join(columns, ", "), placeholders)
+}
+
+func onConflict(table string, keyColumns, columns, updateColumns []string) string {
+	statement := fmt.Sprintf("%s on conflict (%s)", insert(table, columns), strings.Join(keyColumns, ", "))
+	if len(updateColumns) == 0 {
+		return statement + " do nothing"
+	}
+	assignments := make([]string, 0, len(updateColumns))
+	for _, column := range updateColumns {
+		assignments = append(assignments, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+	return fmt.Sprintf("%s do update set %s", statement, strings.Join(assignments, ", "))
+}
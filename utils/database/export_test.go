@@ -0,0 +1,7 @@
+package database
+
+// InTransactionWithDB exposes the transaction helper against any database for the tests
+var InTransactionWithDB = inTransaction
+
+// GetReplicaConfig exposes the configuration of the replicas for the tests
+var GetReplicaConfig = getReplicaConfig
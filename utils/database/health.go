@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// monitor keeps track of whether the primary database is reachable
+type monitor struct {
+	available int32
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+var health = &monitor{}
+
+// IsAvailable is used to check whether the primary database was reachable at the last health check
+func IsAvailable() bool {
+	return atomic.LoadInt32(&health.available) == 1
+}
+
+// connect is used to ping the database with an exponential backoff until it is reachable or the timeout elapses
+// Without a timeout the database is pinged just once.
+func connect(ctx context.Context, db *sql.DB, timeout, backoff time.Duration) error {
+	if timeout <= 0 {
+		return db.PingContext(ctx)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		pingCtx, cancel := context.WithDeadline(ctx, deadline)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if backoff <= 0 || time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		log.Warn(ctx).Err(err).Msgf("database unreachable, retrying in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// startMonitor is used to keep checking the database every interval, the default one when none is configured,
+// as the database would otherwise never be seen coming back once it is unreachable
+func startMonitor(db *sql.DB, available bool, interval time.Duration) *monitor {
+	m := &monitor{stop: make(chan struct{})}
+	m.setAvailable(context.Background(), available, nil)
+	if interval <= 0 {
+		interval = constants.DefaultDatabaseHealthCheckInterval
+	}
+	m.wg.Add(1)
+	go m.run(db, interval)
+	return m
+}
+
+func (m *monitor) run(db *sql.DB, interval time.Duration) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := db.PingContext(ctx)
+			cancel()
+			m.setAvailable(context.Background(), err == nil, err)
+		}
+	}
+}
+
+func (m *monitor) setAvailable(ctx context.Context, available bool, err error) {
+	var value int32
+	if available {
+		value = 1
+	}
+	previous := atomic.SwapInt32(&m.available, value)
+	switch {
+	case previous == value:
+	case available:
+		log.Info(ctx).Msg("database is reachable")
+	default:
+		log.Error(ctx).Err(err).Msg("database is unreachable")
+	}
+}
+
+func (m *monitor) close() {
+	if m.stop != nil {
+		close(m.stop)
+	}
+	m.wg.Wait()
+}
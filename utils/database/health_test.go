@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartupInDegradedModeAndRecovery(t *testing.T) {
+	registerRouting()
+	routing.setDown("primary", true)
+	defer routing.setDown("primary", false)
+
+	start := time.Now()
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{
+		Driver:              "routing",
+		Server:              "primary",
+		Port:                3306,
+		StartupTimeout:      100 * time.Millisecond,
+		StartupBackoff:      10 * time.Millisecond,
+		HealthCheckInterval: 10 * time.Millisecond,
+	}))
+	defer func() {
+		assert.NoError(t, database.Close())
+	}()
+	assert.True(t, time.Since(start) >= 70*time.Millisecond)
+	assert.False(t, database.IsAvailable())
+
+	routing.setDown("primary", false)
+	assert.Eventually(t, database.IsAvailable, time.Second, 10*time.Millisecond)
+
+	routing.setDown("primary", true)
+	assert.Eventually(t, func() bool {
+		return !database.IsAvailable()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStartupAvailable(t *testing.T) {
+	registerRouting()
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{
+		Driver: "routing",
+		Server: "primary",
+		Port:   3306,
+	}))
+	assert.True(t, database.IsAvailable())
+	assert.NoError(t, database.Close())
+	assert.False(t, database.IsAvailable())
+}
+
+func TestMonitorWithoutInterval(t *testing.T) {
+	registerRouting()
+	routing.setDown("primary", true)
+	defer routing.setDown("primary", false)
+
+	// the default interval applies, the monitor is never left out
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{
+		Driver: "routing",
+		Server: "primary",
+		Port:   3306,
+	}))
+	defer func() {
+		assert.NoError(t, database.Close())
+	}()
+	assert.False(t, database.IsAvailable())
+	routing.setDown("primary", false)
+	assert.Eventually(t, database.IsAvailable, 2*constants.DefaultDatabaseHealthCheckInterval, 10*time.Millisecond)
+}
@@ -0,0 +1,350 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"strings"
+	"sync"
+	"time"
+)
+
+const queryNameContextKey contextKey = iota + 1
+
+var slowQueryThreshold time.Duration
+
+// WithQueryName is used to label the queries made with the returned context in the metrics and logs
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameContextKey, name)
+}
+
+// PoolStats is the snapshot of the connection pool statistics
+type PoolStats struct {
+	MaxOpenConnections int           `json:"maxOpenConnections"`
+	OpenConnections    int           `json:"openConnections"`
+	InUse              int           `json:"inUse"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"waitCount"`
+	WaitDuration       time.Duration `json:"waitDuration"`
+	MaxIdleClosed      int64         `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64         `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64         `json:"maxLifetimeClosed"`
+}
+
+// GetPoolStats is used to get the connection pool statistics of the primary and every replica
+func GetPoolStats() map[string]PoolStats {
+	stats := make(map[string]PoolStats, len(replicas.replicas)+1)
+	if db != nil {
+		stats[constants.PrimaryDatabase] = getPoolStats(db)
+	}
+	for _, r := range replicas.replicas {
+		stats[r.address] = getPoolStats(r.db)
+	}
+	return stats
+}
+
+func getPoolStats(db *sql.DB) PoolStats {
+	s := db.Stats()
+	return PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// open is used to open the database with the driver wrapped to record every query
+func open(driverName, dsn string) (*sql.DB, *instrumentedConnector, error) {
+	// the driver registered against the name can only be looked up through a database handle
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &instrumentedConnector{driver: probe.Driver()}
+	_ = probe.Close()
+
+	if err = c.redial(dsn); err != nil {
+		return nil, nil, err
+	}
+	return sql.OpenDB(c), c, nil
+}
+
+func observe(ctx context.Context, query string, start time.Time, err error) {
+	latency := time.Since(start)
+	name := getQueryName(ctx, query)
+	metrics.GetHistogram(fmt.Sprintf(constants.DatabaseQueryLatencyMetric, name)).ObserveDuration(latency)
+	if err != nil && !errors.Is(err, driver.ErrSkip) && !errors.Is(err, sql.ErrNoRows) {
+		metrics.GetCounter(fmt.Sprintf(constants.DatabaseQueryErrorsMetric, name)).Inc()
+	}
+	if slowQueryThreshold > 0 && latency >= slowQueryThreshold {
+		log.Warn(ctx).
+			Str(constants.QueryNameKey, name).
+			Str(constants.QueryKey, query).
+			Dur(constants.LatencyKey, latency).
+			Msg("slow query")
+	}
+}
+
+// getQueryName falls back to the statement type for the queries which are not named
+func getQueryName(ctx context.Context, query string) string {
+	if name, ok := ctx.Value(queryNameContextKey).(string); ok && name != "" {
+		return name
+	}
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return constants.UnknownQueryName
+	}
+	return strings.ToLower(fields[0])
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// instrumentedConnector hands out instrumented connections and can be pointed at a new data source,
+// after which the connections made to the previous one are discarded as they come back to the pool
+type instrumentedConnector struct {
+	driver     driver.Driver
+	mu         sync.RWMutex
+	connector  driver.Connector
+	generation int64
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.RLock()
+	connector, generation := c.connector, c.generation
+	c.mu.RUnlock()
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{conn: conn, connector: c, generation: generation}, nil
+}
+
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func (c *instrumentedConnector) redial(dsn string) error {
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: c.driver}
+	if driverContext, ok := c.driver.(driver.DriverContext); ok {
+		var err error
+		connector, err = driverContext.OpenConnector(dsn)
+		if err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connector = connector
+	c.generation++
+	return nil
+}
+
+func (c *instrumentedConnector) isStale(generation int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return generation != c.generation
+}
+
+// instrumentedConn records the statements run directly on the connection,
+// falling back to the behaviour database/sql has for the capabilities the driver does not have
+type instrumentedConn struct {
+	conn       driver.Conn
+	connector  *instrumentedConnector
+	generation int64
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{stmt: stmt, query: query}, nil
+}
+
+func (c *instrumentedConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *instrumentedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, options)
+	}
+	if options.ReadOnly || options.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default transaction options")
+	}
+	return c.conn.Begin() //nolint:staticcheck // fallback for the drivers not supporting contexts
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	observe(ctx, query, start, err)
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	observe(ctx, query, start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if c.connector.isStale(c.generation) {
+		return driver.ErrBadConn
+	}
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if c.connector.isStale(c.generation) {
+		return false
+	}
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+type instrumentedStmt struct {
+	stmt  driver.Stmt
+	query string
+}
+
+func (s *instrumentedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *instrumentedStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *instrumentedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *instrumentedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if execer, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		values, err = toValues(args)
+		if err == nil {
+			result, err = s.stmt.Exec(values) //nolint:staticcheck // fallback for the drivers not supporting contexts
+		}
+	}
+	observe(ctx, s.query, start, err)
+	return result, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		values, err = toValues(args)
+		if err == nil {
+			rows, err = s.stmt.Query(values) //nolint:staticcheck // fallback for the drivers not supporting contexts
+		}
+	}
+	observe(ctx, s.query, start, err)
+	return rows, err
+}
+
+func (s *instrumentedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func toNamedValues(values []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(values))
+	for i, value := range values {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+	return named
+}
+
+func toValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, value := range named {
+		if value.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of named parameters")
+		}
+		values[i] = value.Value
+	}
+	return values, nil
+}
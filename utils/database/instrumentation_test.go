@@ -0,0 +1,61 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func queryCount(name string) int64 {
+	return metrics.GetSnapshot().Histograms["database.query."+name+".latency"].Count
+}
+
+func TestQueryMetrics(t *testing.T) {
+	initRoutingDatabase(t, constants.RoundRobinReplicaStrategy)
+	ctx := context.Background()
+
+	before := queryCount("touchCounter")
+	assert.NoError(t, database.InTransaction(database.WithQueryName(ctx, "touchCounter"), nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(database.WithQueryName(ctx, "touchCounter"), "update counter set count = 1")
+		return err
+	}))
+	assert.Equal(t, before+1, queryCount("touchCounter"))
+
+	// the queries without a name are labelled by the statement type
+	assert.NoError(t, database.InTransaction(ctx, nil, exec))
+	assert.NotZero(t, queryCount("update"))
+
+	errors := metrics.GetCounter("database.query.readCounter.errors").Value()
+	assert.Error(t, database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(database.WithQueryName(ctx, "readCounter"), "select count from counter").Scan(new(int))
+	}))
+	assert.Equal(t, errors+1, metrics.GetCounter("database.query.readCounter.errors").Value())
+}
+
+func TestPoolStats(t *testing.T) {
+	initRoutingDatabase(t, constants.RoundRobinReplicaStrategy)
+	assert.NoError(t, database.InTransaction(context.Background(), nil, exec))
+
+	stats := database.GetPoolStats()
+	assert.Len(t, stats, 3)
+	assert.Contains(t, stats, constants.PrimaryDatabase)
+	assert.Contains(t, stats, "replica1:3306")
+	// no idle connections are configured so every connection is closed once it is released
+	assert.NotZero(t, stats[constants.PrimaryDatabase].MaxIdleClosed)
+	assert.Contains(t, metrics.GetSnapshot().Gauges, constants.DatabasePoolMetric)
+}
+
+func TestConfigurePool(t *testing.T) {
+	initRoutingDatabase(t, constants.RoundRobinReplicaStrategy)
+
+	// the pool sizes are applied to the primary and the replicas alike
+	database.ConfigurePool(database.Config{MaxOpenConnections: 7, MaxIdleConnections: 2})
+	for name, stats := range database.GetPoolStats() {
+		assert.Equal(t, 7, stats.MaxOpenConnections, name)
+	}
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type contextKey int
+
+const primaryContextKey contextKey = iota
+
+// replica is a read only copy of the primary database along with its last known health
+type replica struct {
+	address   string
+	db        *sql.DB
+	connector *instrumentedConnector
+	healthy   int32
+	latency   int64
+}
+
+// ReplicaStatus is the health of a replica as seen by the last health checks
+type ReplicaStatus struct {
+	Address string        `json:"address"`
+	Healthy bool          `json:"healthy"`
+	Latency time.Duration `json:"latency"`
+}
+
+type replicaSet struct {
+	replicas []*replica
+	strategy string
+	next     uint64
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+var replicas = &replicaSet{}
+
+// WithPrimary is used to pin all the reads made with the returned context to the primary
+// This gives read your writes consistency for the request at the cost of load on the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey, true)
+}
+
+func isPinnedToPrimary(ctx context.Context) bool {
+	pinned, _ := ctx.Value(primaryContextKey).(bool)
+	return pinned
+}
+
+// GetReplicaStatuses is used to get the health of all the configured replicas
+func GetReplicaStatuses() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(replicas.replicas))
+	for _, r := range replicas.replicas {
+		statuses = append(statuses, ReplicaStatus{
+			Address: r.address,
+			Healthy: atomic.LoadInt32(&r.healthy) == 1,
+			Latency: time.Duration(atomic.LoadInt64(&r.latency)),
+		})
+	}
+	return statuses
+}
+
+func getDB(ctx context.Context, options *sql.TxOptions) *sql.DB {
+	if options != nil && options.ReadOnly && !isPinnedToPrimary(ctx) {
+		if r := replicas.pick(); r != nil {
+			return r.db
+		}
+	}
+	return db
+}
+
+func openReplicas(ctx context.Context, d Dialect, driver string, config Config) (*replicaSet, error) {
+	if config.ReplicaStrategy != "" && config.ReplicaStrategy != constants.RoundRobinReplicaStrategy &&
+		config.ReplicaStrategy != constants.LeastLatencyReplicaStrategy {
+		return nil, fmt.Errorf("invalid replica strategy %s provided", config.ReplicaStrategy)
+	}
+	set := &replicaSet{
+		replicas: make([]*replica, 0, len(config.Replicas)),
+		strategy: config.ReplicaStrategy,
+		stop:     make(chan struct{}),
+	}
+	for _, address := range config.Replicas {
+		r, err := openReplica(d, driver, config, address)
+		if err != nil {
+			_ = set.close()
+			return nil, err
+		}
+		set.replicas = append(set.replicas, r)
+	}
+
+	// an unreachable replica is not fatal, it is skipped until it passes a health check
+	interval := config.ReplicaHealthCheckInterval
+	if interval <= 0 {
+		interval = constants.DefaultDatabaseHealthCheckInterval
+	}
+	set.checkHealth(ctx, interval)
+	if len(set.replicas) > 0 {
+		set.wg.Add(1)
+		go set.runHealthChecks(interval)
+	}
+	return set, nil
+}
+
+func openReplica(d Dialect, driver string, config Config, address string) (*replica, error) {
+	replicaConfig, err := getReplicaConfig(config, address)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.Configure(replicaConfig); err != nil {
+		return nil, fmt.Errorf("invalid replica configuration %s: %w", address, err)
+	}
+	replicaDB, replicaConnector, err := open(driver, d.DSN(replicaConfig))
+	if err != nil {
+		return nil, err
+	}
+	configurePool(replicaDB, config)
+	return &replica{address: address, db: replicaDB, connector: replicaConnector, healthy: 1}, nil
+}
+
+// getReplicaConfig is used to get the configuration of the primary pointed at the replica address
+// The server name configured is the one of the primary, so the replica is verified against its own host.
+func getReplicaConfig(config Config, address string) (Config, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return config, fmt.Errorf("invalid replica address %s: %w", address, err)
+	}
+	config.Server = host
+	config.TLS.ServerName = ""
+	config.Port, err = strconv.Atoi(port)
+	if err != nil {
+		return config, fmt.Errorf("invalid replica port %s: %w", address, err)
+	}
+	return config, nil
+}
+
+func (s *replicaSet) pick() *replica {
+	healthy := make([]*replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	if s.strategy == constants.LeastLatencyReplicaStrategy {
+		fastest := healthy[0]
+		for _, r := range healthy[1:] {
+			if atomic.LoadInt64(&r.latency) < atomic.LoadInt64(&fastest.latency) {
+				fastest = r
+			}
+		}
+		return fastest
+	}
+	return healthy[atomic.AddUint64(&s.next, 1)%uint64(len(healthy))]
+}
+
+func (s *replicaSet) runHealthChecks(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkHealth(context.Background(), interval)
+		}
+	}
+}
+
+func (s *replicaSet) checkHealth(ctx context.Context, timeout time.Duration) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := r.db.PingContext(pingCtx)
+		cancel()
+		if err != nil {
+			if atomic.SwapInt32(&r.healthy, 0) == 1 {
+				log.Warn(ctx).Err(err).Msgf("replica %s failed health check, reads fall back to primary", r.address)
+			}
+			continue
+		}
+		r.observeLatency(time.Since(start))
+		if atomic.SwapInt32(&r.healthy, 1) == 0 {
+			log.Info(ctx).Msgf("replica %s is healthy", r.address)
+		}
+	}
+}
+
+// observeLatency keeps a moving average of the latency so a single slow ping does not move the reads
+func (r *replica) observeLatency(latency time.Duration) {
+	previous := atomic.LoadInt64(&r.latency)
+	if previous == 0 {
+		atomic.StoreInt64(&r.latency, int64(latency))
+		return
+	}
+	atomic.StoreInt64(&r.latency, (previous*4+int64(latency))/5)
+}
+
+func (s *replicaSet) close() error {
+	if s.stop != nil {
+		close(s.stop)
+	}
+	s.wg.Wait()
+	var err error
+	for _, r := range s.replicas {
+		if closeErr := r.db.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
+}
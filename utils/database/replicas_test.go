@@ -0,0 +1,170 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/database/databasetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// routingDriver records the data source each statement ran against and fails pings for the ones marked down
+type routingDriver struct {
+	mu   sync.Mutex
+	down map[string]bool
+	used []string
+}
+
+func (d *routingDriver) setDown(host string, down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.down[host] = down
+}
+
+func (d *routingDriver) last() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.used[len(d.used)-1]
+}
+
+func (d *routingDriver) ping(dsn string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for host, down := range d.down {
+		if down && strings.Contains(dsn, "("+host+":") {
+			return errors.New("connection refused")
+		}
+	}
+	return nil
+}
+
+func (d *routingDriver) exec(dsn, _ string, _ []driver.Value) (driver.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.used = append(d.used, dsn)
+	return driver.RowsAffected(1), nil
+}
+
+func (d *routingDriver) query(string, string, []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var routing = &routingDriver{down: make(map[string]bool)}
+
+func registerRouting() {
+	databasetest.Register("routing", databasetest.Handlers{
+		Exec:  routing.exec,
+		Query: routing.query,
+		Ping:  routing.ping,
+	})
+}
+
+func initRoutingDatabase(t *testing.T, strategy string) {
+	registerRouting()
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{
+		Driver:                     "routing",
+		Server:                     "primary",
+		Port:                       3306,
+		Name:                       "counter",
+		Replicas:                   []string{"replica1:3306", "replica2:3306"},
+		ReplicaStrategy:            strategy,
+		ReplicaHealthCheckInterval: 10 * time.Millisecond,
+	}))
+	t.Cleanup(func() {
+		routing.setDown("replica1", false)
+		routing.setDown("replica2", false)
+		assert.NoError(t, database.Close())
+	})
+}
+
+func read(t *testing.T, ctx context.Context) string {
+	assert.NoError(t, database.InTransaction(ctx, &sql.TxOptions{ReadOnly: true}, exec))
+	return routing.last()
+}
+
+func TestReplicaRoundRobin(t *testing.T) {
+	initRoutingDatabase(t, constants.RoundRobinReplicaStrategy)
+	ctx := context.Background()
+
+	first, second := read(t, ctx), read(t, ctx)
+	assert.Contains(t, first, "(replica")
+	assert.Contains(t, second, "(replica")
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, first, read(t, ctx))
+
+	// writes and reads pinned to the primary never go to the replicas
+	assert.NoError(t, database.InTransaction(ctx, nil, exec))
+	assert.Contains(t, routing.last(), "(primary:3306)")
+	assert.Contains(t, read(t, database.WithPrimary(ctx)), "(primary:3306)")
+}
+
+func TestReplicaFallbackToPrimary(t *testing.T) {
+	initRoutingDatabase(t, constants.LeastLatencyReplicaStrategy)
+	ctx := context.Background()
+	assert.Contains(t, read(t, ctx), "(replica")
+
+	routing.setDown("replica1", true)
+	routing.setDown("replica2", true)
+	assert.Eventually(t, func() bool {
+		for _, status := range database.GetReplicaStatuses() {
+			if status.Healthy {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, read(t, ctx), "(primary:3306)")
+
+	routing.setDown("replica2", false)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(read(t, ctx), "(replica2:3306)")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReplicaHealthChecksWithoutInterval(t *testing.T) {
+	registerRouting()
+	routing.setDown("replica1", true)
+	defer routing.setDown("replica1", false)
+
+	// the default interval applies, the replica down at startup is checked again
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{
+		Driver:   "routing",
+		Server:   "primary",
+		Port:     3306,
+		Replicas: []string{"replica1:3306"},
+	}))
+	defer func() {
+		assert.NoError(t, database.Close())
+	}()
+	assert.Equal(t, []database.ReplicaStatus{{Address: "replica1:3306"}}, database.GetReplicaStatuses())
+	routing.setDown("replica1", false)
+	assert.Eventually(t, func() bool {
+		return database.GetReplicaStatuses()[0].Healthy
+	}, 2*constants.DefaultDatabaseHealthCheckInterval, 10*time.Millisecond)
+	assert.Contains(t, read(t, context.Background()), "(replica1:3306)")
+}
+
+func TestInvalidReplicaConfig(t *testing.T) {
+	registerRouting()
+	err := database.InitDatabase(context.Background(), database.Config{
+		Driver:          "routing",
+		Server:          "primary",
+		Replicas:        []string{"replica1"},
+		ReplicaStrategy: constants.RoundRobinReplicaStrategy,
+	})
+	assert.Error(t, err)
+	err = database.InitDatabase(context.Background(), database.Config{
+		Driver:          "routing",
+		Server:          "primary",
+		ReplicaStrategy: "random",
+	})
+	assert.Error(t, err)
+}
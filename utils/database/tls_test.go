@@ -0,0 +1,118 @@
+package database_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate is used to write a self signed certificate and its key, returning their paths
+func writeCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "database"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE",
+		Bytes: certificate}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY",
+		Bytes: keyBytes}), 0600))
+	return certFile, keyFile
+}
+
+func TestMySQLDSNOptions(t *testing.T) {
+	d := getDialect(t, constants.MySQLDialect)
+	config := database.Config{
+		Server:       "localhost",
+		Port:         3306,
+		Name:         "counter",
+		Username:     "user",
+		Password:     "pass",
+		TLS:          database.TLSConfig{Mode: constants.SkipVerifyTLSMode},
+		DialTimeout:  time.Second,
+		ReadTimeout:  2 * time.Second,
+		WriteTimeout: 3 * time.Second,
+		ParseTime:    true,
+		Charset:      "utf8mb4",
+		Collation:    "utf8mb4_unicode_ci",
+		Params:       map[string]string{"time_zone": "'+00:00'"},
+	}
+	assert.NoError(t, d.Configure(config))
+	assert.Equal(t, "user:pass@tcp(localhost:3306)/counter?collation=utf8mb4_unicode_ci&parseTime=true"+
+		"&readTimeout=2s&timeout=1s&tls=skip-verify&writeTimeout=3s&charset=utf8mb4&time_zone=%27%2B00%3A00%27",
+		d.DSN(config))
+}
+
+func TestMySQLTLSFiles(t *testing.T) {
+	d := getDialect(t, constants.MySQLDialect)
+	certFile, keyFile := writeCertificate(t)
+	config := database.Config{Server: "localhost", Port: 3306, TLS: database.TLSConfig{
+		Mode:     constants.VerifyTLSMode,
+		CAFile:   certFile,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}}
+	assert.NoError(t, d.Configure(config))
+	assert.Contains(t, d.DSN(config), "tls="+constants.MySQLTLSConfigName+"-localhost-3306")
+
+	config.TLS = database.TLSConfig{Mode: constants.VerifyTLSMode}
+	assert.Contains(t, d.DSN(config), "tls=true")
+}
+
+func TestMySQLTLSReplicas(t *testing.T) {
+	d := getDialect(t, constants.MySQLDialect)
+	certFile, _ := writeCertificate(t)
+	config := database.Config{Server: "primary", Port: 3306, TLS: database.TLSConfig{
+		Mode:       constants.VerifyTLSMode,
+		CAFile:     certFile,
+		ServerName: "primary.db",
+	}}
+	assert.NoError(t, d.Configure(config))
+
+	// every host has its own TLS config, the replicas being verified against their own hosts
+	replicaConfig, err := database.GetReplicaConfig(config, "replica:3307")
+	assert.NoError(t, err)
+	assert.Equal(t, "replica", replicaConfig.Server)
+	assert.Empty(t, replicaConfig.TLS.ServerName)
+	assert.NoError(t, d.Configure(replicaConfig))
+	assert.Contains(t, d.DSN(config), "tls="+constants.MySQLTLSConfigName+"-primary-3306")
+	assert.Contains(t, d.DSN(replicaConfig), "tls="+constants.MySQLTLSConfigName+"-replica-3307")
+}
+
+func TestInvalidMySQLOptions(t *testing.T) {
+	d := getDialect(t, constants.MySQLDialect)
+	certFile, keyFile := writeCertificate(t)
+	for name, config := range map[string]database.Config{
+		"mode":                    {TLS: database.TLSConfig{Mode: "always"}},
+		"files without verify":    {TLS: database.TLSConfig{Mode: constants.PreferredTLSMode, CAFile: certFile}},
+		"certificate without key": {TLS: database.TLSConfig{Mode: constants.VerifyTLSMode, CertFile: certFile}},
+		"key as CA":               {TLS: database.TLSConfig{Mode: constants.VerifyTLSMode, CAFile: keyFile}},
+		"missing CA":              {TLS: database.TLSConfig{Mode: constants.VerifyTLSMode, CAFile: certFile + ".missing"}},
+		"negative timeout":        {ReadTimeout: -time.Second},
+	} {
+		assert.Error(t, d.Configure(config), name)
+	}
+}
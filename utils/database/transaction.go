@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"time"
+)
+
+// RetryPolicy is the set of configurable parameters for retrying transactions
+type RetryPolicy struct {
+	MaxRetries int           `json:"maxRetries"`
+	Backoff    time.Duration `json:"backoff"`
+}
+
+var retryPolicy RetryPolicy
+
+// InTransaction is used to run the function inside a transaction
+// The transaction is committed when the function succeeds and rolled back when it returns an error or panics.
+// Read only transactions are routed to a healthy replica unless the context is pinned to the primary.
+// Transactions failing with a deadlock or a serialization failure are retried as per the configured policy,
+// so the function should not have side effects outside the transaction.
+func InTransaction(ctx context.Context, options *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	return inTransaction(ctx, getDB(ctx, options), retryPolicy, options, fn)
+}
+
+func inTransaction(ctx context.Context, db *sql.DB, policy RetryPolicy, options *sql.TxOptions,
+	fn func(tx *sql.Tx) error) error {
+	backoff := policy.Backoff
+	for attempt := 0; ; attempt++ {
+		err := runTransaction(ctx, db, options, fn)
+		if err == nil || attempt >= policy.MaxRetries || !IsRetryableError(err) {
+			return err
+		}
+		log.Warn(ctx).Err(err).Msgf("retrying transaction, attempt %d", attempt+1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func runTransaction(ctx context.Context, db *sql.DB, options *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(ctx, tx)
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		rollback(ctx, tx)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func rollback(ctx context.Context, tx *sql.Tx) {
+	err := tx.Rollback()
+	if err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Error(ctx).Err(err).Msg("error rolling back transaction")
+	}
+}
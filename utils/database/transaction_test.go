@@ -0,0 +1,161 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/database/databasetest"
+	"github.com/stretchr/testify/assert"
+)
+
+var fake *databasetest.Driver
+
+func openFakeDatabase(t *testing.T, execHandler func(query string) error) *sql.DB {
+	fake = databasetest.Register("fake", databasetest.Handlers{
+		Exec: func(_, query string, _ []driver.Value) (driver.Result, error) {
+			if execHandler != nil {
+				if err := execHandler(query); err != nil {
+					return nil, err
+				}
+			}
+			return driver.RowsAffected(1), nil
+		},
+	})
+	db, err := sql.Open("fake", "")
+	assert.NoError(t, err)
+	// with a single connection any leaked transaction blocks every transaction after it
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db
+}
+
+func assertNoLeaks(t *testing.T, db *sql.DB) {
+	assert.Equal(t, 0, db.Stats().InUse)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	conn, err := db.Conn(ctx)
+	assert.NoError(t, err)
+	if conn != nil {
+		assert.NoError(t, conn.Close())
+	}
+}
+
+func exec(tx *sql.Tx) error {
+	_, err := tx.Exec("update counter set count = 1 where id = 'a'")
+	return err
+}
+
+func TestInTransactionCommit(t *testing.T) {
+	db := openFakeDatabase(t, nil)
+	err := database.InTransactionWithDB(context.Background(), db, database.RetryPolicy{}, nil, exec)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.GetCommitted())
+	assert.Equal(t, 0, fake.GetRolledBack())
+	assertNoLeaks(t, db)
+}
+
+func TestInTransactionRollbackOnError(t *testing.T) {
+	db := openFakeDatabase(t, nil)
+	expected := errors.New("counter does not exist")
+	err := database.InTransactionWithDB(context.Background(), db, database.RetryPolicy{}, nil,
+		func(*sql.Tx) error {
+			return expected
+		})
+	assert.Equal(t, expected, err)
+	assert.Equal(t, 0, fake.GetCommitted())
+	assert.Equal(t, 1, fake.GetRolledBack())
+	assertNoLeaks(t, db)
+}
+
+func TestInTransactionRollbackOnPanic(t *testing.T) {
+	db := openFakeDatabase(t, nil)
+	assert.Panics(t, func() {
+		_ = database.InTransactionWithDB(context.Background(), db, database.RetryPolicy{}, nil,
+			func(*sql.Tx) error {
+				panic("boom")
+			})
+	})
+	assert.Equal(t, 1, fake.GetRolledBack())
+	assertNoLeaks(t, db)
+}
+
+func TestInTransactionReadOnlyQuery(t *testing.T) {
+	db := openFakeDatabase(t, nil)
+	err := database.InTransactionWithDB(context.Background(), db, database.RetryPolicy{},
+		&sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+			var count int
+			return tx.QueryRow("select count from counter where id = 'a'").Scan(&count)
+		})
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.Equal(t, 1, fake.GetRolledBack())
+	assertNoLeaks(t, db)
+}
+
+func TestInTransactionRetriesDeadlock(t *testing.T) {
+	attempts := 0
+	db := openFakeDatabase(t, func(string) error {
+		attempts++
+		if attempts < 3 {
+			return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+		}
+		return nil
+	})
+	err := database.InTransactionWithDB(context.Background(), db,
+		database.RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond}, nil, exec)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 1, fake.GetCommitted())
+	assert.Equal(t, 2, fake.GetRolledBack())
+	assertNoLeaks(t, db)
+}
+
+func TestInTransactionRetriesExhausted(t *testing.T) {
+	attempts := 0
+	db := openFakeDatabase(t, func(string) error {
+		attempts++
+		return &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+	})
+	err := database.InTransactionWithDB(context.Background(), db,
+		database.RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}, nil, exec)
+	assert.True(t, database.IsRetryableError(err))
+	assert.Equal(t, 3, attempts)
+	assertNoLeaks(t, db)
+}
+
+func TestInTransactionDoesNotRetryOtherErrors(t *testing.T) {
+	attempts := 0
+	db := openFakeDatabase(t, func(string) error {
+		attempts++
+		return &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	})
+	err := database.InTransactionWithDB(context.Background(), db,
+		database.RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}, nil, exec)
+	assert.Error(t, err)
+	assert.False(t, database.IsRetryableError(err))
+	assert.Equal(t, 1, attempts)
+	assertNoLeaks(t, db)
+}
+
+func TestInTransactionNoLeaks(t *testing.T) {
+	db := openFakeDatabase(t, nil)
+	for i := 0; i < 50; i++ {
+		_ = database.InTransactionWithDB(context.Background(), db, database.RetryPolicy{}, nil,
+			func(tx *sql.Tx) error {
+				if i%2 == 0 {
+					return errors.New("counter already exists")
+				}
+				return exec(tx)
+			})
+	}
+	assert.Equal(t, 25, fake.GetCommitted())
+	assert.Equal(t, 25, fake.GetRolledBack())
+	assertNoLeaks(t, db)
+}
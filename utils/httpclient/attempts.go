@@ -0,0 +1,276 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// AttemptsConfig is the configuration for the attempts made for a request, on top of the ones of the underlying client
+// The retry count of the underlying client is expected to be zero when the retries are made here.
+type AttemptsConfig struct {
+	// Timeout is how long an attempt can take, while the deadline is how long all the attempts together can take
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	Deadline time.Duration `json:"deadline,omitempty"`
+	// Retries is the number of attempts made again when the request fails, after the retry backoff
+	Retries      int           `json:"retries,omitempty"`
+	RetryBackoff time.Duration `json:"retryBackoff,omitempty"`
+	// HedgeAfterPercentile enables the hedging of the idempotent requests, another attempt being made when
+	// the ones made take longer than this percentile of the latencies of the recent attempts, or the hedge delay
+	HedgeAfterPercentile float64       `json:"hedgeAfterPercentile,omitempty"`
+	HedgeDelay           time.Duration `json:"hedgeDelay,omitempty"`
+	MaxHedges            int           `json:"maxHedges,omitempty"`
+	// RetryBudgetPercent caps the retries and the hedges to this percent of the recent requests, besides the
+	// minimum retries per second always allowed, there is no cap when it is zero
+	RetryBudgetPercent  float64 `json:"retryBudgetPercent,omitempty"`
+	MinRetriesPerSecond int     `json:"minRetriesPerSecond,omitempty"`
+}
+
+// attemptsWindow is how long the rolling numbers of hystrix keep the requests and the retries for
+const attemptsWindow = 10
+
+// result is the result of an attempt
+type result struct {
+	id       int
+	response *http.Response
+	err      error
+}
+
+// cancelOnClose cancels the context of the attempt once the body of its response is read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (c AttemptsConfig) isEnabled() bool {
+	return c.Timeout > 0 || c.Deadline > 0 || c.Retries > 0 || c.HedgeAfterPercentile > 0
+}
+
+// attempt makes the attempts for the request, with the timeouts, retries and hedges configured for it
+func attempt(config *RequestConfig, next Handler) Handler {
+	c := config.attempts
+	if !c.isEnabled() {
+		return next
+	}
+	u := getUpstream(config.name)
+	maxHedges := c.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	retries := metrics.GetCounter(fmt.Sprintf(constants.HTTPRetriesMetric, config.name))
+	hedges := metrics.GetCounter(fmt.Sprintf(constants.HTTPHedgesMetric, config.name))
+	exhausted := metrics.GetCounter(fmt.Sprintf(constants.HTTPRetryBudgetExhaustedMetric, config.name))
+
+	return func(request *Request) (*http.Response, error) {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if c.Deadline > 0 {
+			ctx, cancel = context.WithTimeout(request.getContext(), c.Deadline)
+		} else {
+			ctx, cancel = context.WithCancel(request.getContext())
+		}
+		// the upstream may have acted on the failed attempts of the requests which are not idempotent, so they are
+		// neither retried nor hedged
+		idempotent := isIdempotent(request.Method())
+		// every attempt gets the body afresh, the hedges being made at the same time
+		var body []byte
+		if request.body != nil {
+			var err error
+			if body, err = ioutil.ReadAll(request.body); err != nil {
+				cancel()
+				return nil, err
+			}
+		}
+		u.requests.Increment(1)
+
+		results := make(chan result, 1+c.Retries+maxHedges)
+		cancels := make([]context.CancelFunc, 0, 1+c.Retries+maxHedges)
+		launch := func() {
+			var attemptCtx context.Context
+			var attemptCancel context.CancelFunc
+			if c.Timeout > 0 {
+				attemptCtx, attemptCancel = context.WithTimeout(ctx, c.Timeout)
+			} else {
+				attemptCtx, attemptCancel = context.WithCancel(ctx)
+			}
+			r := request.clone(attemptCtx)
+			if body != nil {
+				r.body = bytes.NewReader(body)
+			}
+			id := len(cancels)
+			cancels = append(cancels, attemptCancel)
+			go func() {
+				start := time.Now()
+				response, err := next(r)
+				if err == nil && response.StatusCode < http.StatusInternalServerError {
+					u.latencies.Add(time.Since(start))
+				}
+				results <- result{id: id, response: response, err: err}
+			}()
+		}
+
+		launch()
+		inFlight, retried, hedged := 1, 0, 0
+		var hedge <-chan time.Time
+		if idempotent && c.HedgeAfterPercentile > 0 {
+			timer := time.NewTimer(u.getHedgeDelay(c))
+			defer timer.Stop()
+			hedge = timer.C
+		}
+		var last result
+		for inFlight > 0 {
+			select {
+			case <-hedge:
+				hedge = nil
+				if hedged >= maxHedges || ctx.Err() != nil {
+					continue
+				}
+				if !u.withdrawRetry(c) {
+					exhausted.Inc()
+					continue
+				}
+				hedges.Inc()
+				launch()
+				inFlight, hedged = inFlight+1, hedged+1
+				if hedged < maxHedges {
+					hedge = time.After(u.getHedgeDelay(c))
+				}
+			case r := <-results:
+				inFlight--
+				if r.err == nil && r.response.StatusCode < http.StatusInternalServerError {
+					// the first success is taken, the rest of the attempts are cancelled
+					discard(results, inFlight, cancels, r.id)
+					return withCancel(r.response, cancels[r.id], cancel), nil
+				}
+				last.close()
+				last = r
+				if inFlight > 0 || !idempotent || retried >= c.Retries || !isRetryable(ctx, r.err) {
+					continue
+				}
+				if !u.withdrawRetry(c) {
+					exhausted.Inc()
+					continue
+				}
+				if !sleep(ctx, c.RetryBackoff) {
+					continue
+				}
+				log.Debug(ctx).Err(r.err).Str(constants.RequestNameKey, config.name).Msg("retrying http request")
+				retries.Inc()
+				last.close()
+				last = result{}
+				launch()
+				inFlight, retried = inFlight+1, retried+1
+			}
+		}
+		// all the attempts failed, the last failure is what is returned
+		for id, cancelAttempt := range cancels {
+			if last.err != nil || id != last.id {
+				cancelAttempt()
+			}
+		}
+		if last.err != nil {
+			cancel()
+			return nil, last.err
+		}
+		return withCancel(last.response, cancels[last.id], cancel), nil
+	}
+}
+
+func (u *upstream) getHedgeDelay(c AttemptsConfig) time.Duration {
+	delay := time.Duration(u.latencies.Percentile(c.HedgeAfterPercentile)) * time.Millisecond
+	if delay < c.HedgeDelay {
+		delay = c.HedgeDelay
+	}
+	return delay
+}
+
+// withdrawRetry reports whether the budget allows another retry or hedge, taking it out of the budget if it does
+func (u *upstream) withdrawRetry(c AttemptsConfig) bool {
+	if c.RetryBudgetPercent > 0 {
+		now := time.Now()
+		allowed := u.requests.Sum(now)*c.RetryBudgetPercent/100 + float64(c.MinRetriesPerSecond*attemptsWindow)
+		if u.retries.Sum(now) >= allowed {
+			return false
+		}
+	}
+	u.retries.Increment(1)
+	return true
+}
+
+// isRetryable reports whether the failed attempt can be made again, which it cannot once the deadline is over
+// or when its circuit rejected it
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var circuitErr hystrix.CircuitError
+	return !errors.As(err, &circuitErr) && !errors.Is(err, ErrCircuitForcedOpen)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// discard cancels the attempts in flight other than the one taken, closing their responses as they come
+func discard(results <-chan result, inFlight int, cancels []context.CancelFunc, taken int) {
+	for id, cancel := range cancels {
+		if id != taken {
+			cancel()
+		}
+	}
+	go func() {
+		for i := 0; i < inFlight; i++ {
+			r := <-results
+			r.close()
+		}
+	}()
+}
+
+func withCancel(response *http.Response, cancels ...context.CancelFunc) *http.Response {
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}}
+	return response
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (r result) close() {
+	if r.response != nil {
+		_ = r.response.Body.Close()
+	}
+}
@@ -0,0 +1,132 @@
+package httpclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// initAttemptsClient starts an upstream taking the delay of the call to respond, or failing when it is negative
+func initAttemptsClient(t *testing.T, name string, delays func(call int64) time.Duration,
+	attempts map[string]interface{}) *upstream {
+	u := &upstream{}
+	u.handler = func(writer http.ResponseWriter, request *http.Request) {
+		delay := delays(u.getCalls())
+		if delay < 0 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-time.After(delay):
+			_, _ = writer.Write([]byte("ok"))
+		case <-request.Context().Done():
+		}
+	}
+	server := httptest.NewServer(u)
+	t.Cleanup(server.Close)
+	httpclient.Init(httpclient.NewRequestConfig(name, map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             server.URL,
+		"timeoutinmillis": 5000,
+		"retrycount":      0,
+		"attempts":        attempts,
+	}))
+	return u
+}
+
+func requestAttempts(name string) (string, error) {
+	response, err := httpclient.Get().Request(httpclient.NewRequest(name))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(response.Body)
+	return string(body), err
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	before := metrics.GetSnapshot().Counters["http.timeouts.retries"]
+	u := initAttemptsClient(t, "timeouts", func(call int64) time.Duration {
+		if call == 1 {
+			return time.Second
+		}
+		return 0
+	}, map[string]interface{}{"timeoutInMillis": 50, "retries": 1})
+
+	start := time.Now()
+	body, err := requestAttempts("timeouts")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", body)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	assert.Equal(t, int64(2), u.getCalls())
+	assert.Equal(t, int64(1), metrics.GetSnapshot().Counters["http.timeouts.retries"]-before)
+}
+
+func TestHedging(t *testing.T) {
+	before := metrics.GetSnapshot().Counters["http.hedged.hedges"]
+	u := initAttemptsClient(t, "hedged", func(call int64) time.Duration {
+		if call == 1 {
+			return time.Second
+		}
+		return 0
+	}, map[string]interface{}{"hedgeAfterPercentile": 95, "hedgeDelayInMillis": 20})
+
+	start := time.Now()
+	body, err := requestAttempts("hedged")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", body)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	assert.Equal(t, int64(2), u.getCalls())
+	assert.Equal(t, int64(1), metrics.GetSnapshot().Counters["http.hedged.hedges"]-before)
+}
+
+func TestDeadline(t *testing.T) {
+	initAttemptsClient(t, "deadline", func(int64) time.Duration {
+		return time.Second
+	}, map[string]interface{}{"deadlineInMillis": 100, "timeoutInMillis": 60, "retries": 5})
+
+	start := time.Now()
+	_, err := requestAttempts("deadline")
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+}
+
+func TestRetryBudget(t *testing.T) {
+	u := initAttemptsClient(t, "budget", func(int64) time.Duration {
+		return -1
+	}, map[string]interface{}{"retries": 3, "retryBudgetPercent": 10})
+
+	for i := 0; i < 20; i++ {
+		_, _ = requestAttempts("budget")
+	}
+	// without the budget there would have been three retries for every request
+	assert.LessOrEqual(t, atomic.LoadInt64(&u.calls), int64(20+3))
+}
+
+func TestRetryOnlyIdempotent(t *testing.T) {
+	u := &upstream{handler: func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}}
+	server := httptest.NewServer(u)
+	t.Cleanup(server.Close)
+	httpclient.Init(httpclient.NewRequestConfig("unsafe", map[string]interface{}{
+		"method":          http.MethodPost,
+		"url":             server.URL,
+		"timeoutinmillis": 5000,
+		"retrycount":      0,
+		"attempts":        map[string]interface{}{"retries": 2, "hedgeAfterPercentile": 95, "hedgeDelayInMillis": 1},
+	}))
+
+	// the upstream may have acted on the failed request, so it is made only once
+	_, _ = requestAttempts("unsafe")
+	assert.Equal(t, int64(1), u.getCalls())
+}
@@ -0,0 +1,359 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/secrets"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signer is used to authenticate the requests to an upstream
+type Signer interface {
+	// Sign is called for every attempt made for the request, right before it is made
+	Sign(request *Request) error
+}
+
+// SignerFactory is used to create the signer for the auth configs of an upstream
+type SignerFactory func(configs map[string]interface{}) (Signer, error)
+
+// ErrUnknownSigner is returned for the upstreams whose auth type has no signer registered
+var ErrUnknownSigner = errors.New("no signer registered")
+
+// token is an access token along with when it is to be refreshed
+type token struct {
+	value     string
+	tokenType string
+	refreshAt time.Time
+}
+
+// tokenSource fetches the access tokens using the client credentials and caches them until they are to be refreshed
+// The sources are kept across the initialisations of the client, for the upstreams not to fetch them again.
+type tokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       string
+	client       *http.Client
+	mu           sync.Mutex
+	token        *token
+}
+
+type bearerSigner struct {
+	token string
+}
+
+type apiKeySigner struct {
+	header string
+	key    string
+}
+
+type oauth2Signer struct {
+	source *tokenSource
+}
+
+type hmacSigner struct {
+	keyID  string
+	secret string
+}
+
+var (
+	signersMu sync.RWMutex
+	factories = map[string]SignerFactory{
+		constants.BearerAuthType: newBearerSigner,
+		constants.APIKeyAuthType: newAPIKeySigner,
+		constants.OAuth2AuthType: newOAuth2Signer,
+		constants.HMACAuthType:   newHMACSigner,
+	}
+
+	tokenSourcesMu sync.Mutex
+	tokenSources   = make(map[string]*tokenSource)
+)
+
+// RegisterSigner is used to register the signer for the upstreams having the auth type
+// It applies to the requests configured by the later calls to Init, and replaces the signer registered for the type.
+func RegisterSigner(authType string, factory SignerFactory) {
+	signersMu.Lock()
+	defer signersMu.Unlock()
+	factories[strings.ToLower(authType)] = factory
+}
+
+// newSigner is used to create the signer for the auth configs, there is none when they are empty
+// The secret references in the configs are resolved when signing, so the rotated secrets are picked up.
+func newSigner(configs map[string]interface{}) (Signer, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	authType := strings.ToLower(getString(configs, constants.HTTPAuthTypeConfigKey))
+	signersMu.RLock()
+	factory, ok := factories[authType]
+	signersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w for the auth type %s", ErrUnknownSigner, authType)
+	}
+	return factory(configs)
+}
+
+// authenticate signs every attempt made for the request with the signer of its auth type
+func authenticate(config *RequestConfig, next Handler) Handler {
+	signer, err := newSigner(config.auth)
+	if err != nil {
+		log.Error(context.Background()).Err(err).Str(constants.RequestNameKey, config.name).
+			Msg("error creating the signer, the requests will fail")
+		return func(*Request) (*http.Response, error) {
+			return nil, err
+		}
+	}
+	if signer == nil {
+		return next
+	}
+	return func(request *Request) (*http.Response, error) {
+		if request.method == "" {
+			request.method = config.method
+		}
+		if err := signer.Sign(request); err != nil {
+			return nil, fmt.Errorf("error signing the %s request: %w", config.name, err)
+		}
+		response, err := next(request)
+		if err == nil && response.StatusCode == http.StatusUnauthorized {
+			// the token could have been revoked before it expired, a new one is fetched for the next request
+			if s, ok := signer.(*oauth2Signer); ok {
+				s.source.invalidate()
+			}
+		}
+		return response, err
+	}
+}
+
+func newBearerSigner(configs map[string]interface{}) (Signer, error) {
+	s := &bearerSigner{token: getString(configs, constants.HTTPAuthTokenConfigKey)}
+	if s.token == "" {
+		return nil, errors.New("bearer auth needs a token")
+	}
+	return s, nil
+}
+
+func (s *bearerSigner) Sign(request *Request) error {
+	value, err := secrets.Resolve(s.token)
+	if err != nil {
+		return err
+	}
+	request.SetHeaderParam(constants.AuthorizationHeader, constants.BearerTokenType+" "+value)
+	return nil
+}
+
+func newAPIKeySigner(configs map[string]interface{}) (Signer, error) {
+	s := &apiKeySigner{
+		header: getString(configs, constants.HTTPAuthHeaderConfigKey),
+		key:    getString(configs, constants.HTTPAuthKeyConfigKey),
+	}
+	if s.header == "" {
+		s.header = constants.APIKeyHeader
+	}
+	if s.key == "" {
+		return nil, errors.New("api key auth needs a key")
+	}
+	return s, nil
+}
+
+func (s *apiKeySigner) Sign(request *Request) error {
+	value, err := secrets.Resolve(s.key)
+	if err != nil {
+		return err
+	}
+	request.SetHeaderParam(s.header, value)
+	return nil
+}
+
+func newOAuth2Signer(configs map[string]interface{}) (Signer, error) {
+	tokenURL := getString(configs, constants.HTTPAuthTokenURLConfigKey)
+	clientID := getString(configs, constants.HTTPAuthClientIDConfigKey)
+	clientSecret := getString(configs, constants.HTTPAuthClientSecretConfigKey)
+	if tokenURL == "" || clientID == "" || clientSecret == "" {
+		return nil, errors.New("oauth2 auth needs a token url, a client id and a client secret")
+	}
+	scopes := getString(configs, constants.HTTPAuthScopesConfigKey)
+	if value, _ := getOption(configs, constants.HTTPAuthScopesConfigKey); value != nil && scopes == "" {
+		if list, ok := value.([]interface{}); ok {
+			parts := make([]string, 0, len(list))
+			for _, scope := range list {
+				parts = append(parts, fmt.Sprint(scope))
+			}
+			scopes = strings.Join(parts, " ")
+		}
+	}
+	timeout := getMillis(configs, constants.HTTPAuthTimeoutInMillisConfigKey)
+	if timeout <= 0 {
+		timeout = constants.DefaultTokenTimeout
+	}
+
+	key := strings.Join([]string{tokenURL, clientID, clientSecret, scopes}, " ")
+	tokenSourcesMu.Lock()
+	defer tokenSourcesMu.Unlock()
+	source, ok := tokenSources[key]
+	if !ok {
+		source = &tokenSource{
+			tokenURL:     tokenURL,
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			scopes:       scopes,
+			client:       &http.Client{Timeout: timeout},
+		}
+		tokenSources[key] = source
+	}
+	return &oauth2Signer{source: source}, nil
+}
+
+func (s *oauth2Signer) Sign(request *Request) error {
+	t, err := s.source.get(request.getContext())
+	if err != nil {
+		return err
+	}
+	request.SetHeaderParam(constants.AuthorizationHeader, t.tokenType+" "+t.value)
+	return nil
+}
+
+// get is used to get the cached token, fetching a new one when it is to be refreshed
+// The requests wait for the one fetching the token rather than all of them fetching it.
+func (s *tokenSource) get(ctx context.Context) (*token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && time.Now().Before(s.token.refreshAt) {
+		return s.token, nil
+	}
+	t, err := s.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.token = t
+	return t, nil
+}
+
+func (s *tokenSource) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = nil
+}
+
+func (s *tokenSource) fetch(ctx context.Context) (*token, error) {
+	clientSecret, err := secrets.Resolve(s.clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{constants.GrantTypeParam: {constants.ClientCredentialsGrantType}}
+	if s.scopes != "" {
+		form.Set(constants.ScopeParam, s.scopes)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set(constants.ContentTypeHeader, constants.FormContentType)
+	request.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(clientSecret))
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the access token: %w", err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, constants.MaxUpstreamBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the access token: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching the access token, status %d: %s", response.StatusCode,
+			strings.TrimSpace(string(body)))
+	}
+	var t struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = json.Unmarshal(body, &t); err != nil {
+		return nil, fmt.Errorf("error decoding the access token: %w", err)
+	}
+	if t.AccessToken == "" {
+		return nil, errors.New("no access token in the token response")
+	}
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, constants.BearerTokenType) {
+		t.TokenType = constants.BearerTokenType
+	}
+	// the token is refreshed a little before it expires, so that it does not expire on the way
+	lifetime := time.Duration(t.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = constants.DefaultTokenLifetime
+	}
+	skew := constants.TokenRefreshSkew
+	if skew > lifetime/2 {
+		skew = lifetime / 2
+	}
+	return &token{value: t.AccessToken, tokenType: t.TokenType, refreshAt: time.Now().Add(lifetime - skew)}, nil
+}
+
+func newHMACSigner(configs map[string]interface{}) (Signer, error) {
+	s := &hmacSigner{
+		keyID:  getString(configs, constants.HTTPAuthKeyIDConfigKey),
+		secret: getString(configs, constants.HTTPAuthSecretConfigKey),
+	}
+	if s.keyID == "" || s.secret == "" {
+		return nil, errors.New("hmac auth needs a key id and a secret")
+	}
+	return s, nil
+}
+
+// Sign signs the method, the path, the query, the timestamp and the hash of the body of the request, separated by
+// new lines, with the sha256 hmac of the secret, the timestamp and the hash of the body being sent as headers too
+func (s *hmacSigner) Sign(request *Request) error {
+	secret, err := secrets.Resolve(s.secret)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(request.url)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	for param, value := range request.query {
+		query.Set(param, value)
+	}
+	var body []byte
+	if request.body != nil {
+		if body, err = ioutil.ReadAll(request.body); err != nil {
+			return err
+		}
+		request.body = bytes.NewReader(body)
+	}
+	bodyHash := sha256.Sum256(body)
+	contentHash := hex.EncodeToString(bodyHash[:])
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(strings.Join([]string{request.Method(), path, query.Encode(), timestamp,
+		contentHash}, "\n")))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	request.SetHeaderParam(constants.TimestampHeader, timestamp)
+	request.SetHeaderParam(constants.ContentSHA256Header, contentHash)
+	request.SetHeaderParam(constants.AuthorizationHeader, fmt.Sprintf("%s keyId=%s, signature=%s",
+		constants.HMACAuthScheme, s.keyID, signature))
+	return nil
+}
@@ -0,0 +1,162 @@
+package httpclient_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// initAuthClient starts an upstream responding with the status of the handler and the authorization it got
+func initAuthClient(t *testing.T, method string, handler func(request *http.Request) int,
+	auth map[string]interface{}) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(handler(request))
+		_, _ = writer.Write([]byte(request.Header.Get("Authorization")))
+	}))
+	t.Cleanup(server.Close)
+	httpclient.Init(httpclient.NewRequestConfig("auth", map[string]interface{}{
+		"method":          method,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+		"auth":            auth,
+	}))
+}
+
+func requestAuth(request *httpclient.Request) (string, int, error) {
+	response, err := httpclient.Get().Request(request)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(response.Body)
+	return string(body), response.StatusCode, err
+}
+
+func ok(*http.Request) int {
+	return http.StatusOK
+}
+
+func TestStaticAuth(t *testing.T) {
+	assert.NoError(t, os.Setenv("AUTH_TEST_TOKEN", "secret"))
+	defer func() {
+		_ = os.Unsetenv("AUTH_TEST_TOKEN")
+	}()
+	initAuthClient(t, http.MethodGet, ok, map[string]interface{}{"type": "bearer", "token": "env:AUTH_TEST_TOKEN"})
+	authorization, _, err := requestAuth(httpclient.NewRequest("auth"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer secret", authorization)
+
+	key := ""
+	initAuthClient(t, http.MethodGet, func(request *http.Request) int {
+		key = request.Header.Get("X-Key")
+		return http.StatusOK
+	}, map[string]interface{}{"type": "apiKey", "header": "X-Key", "key": "env:AUTH_TEST_TOKEN"})
+	_, _, err = requestAuth(httpclient.NewRequest("auth"))
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", key)
+
+	initAuthClient(t, http.MethodGet, ok, map[string]interface{}{"type": "bearer", "token": "env:AUTH_TEST_MISSING"})
+	_, _, err = requestAuth(httpclient.NewRequest("auth"))
+	assert.Error(t, err)
+	initAuthClient(t, http.MethodGet, ok, map[string]interface{}{"type": "kerberos"})
+	_, _, err = requestAuth(httpclient.NewRequest("auth"))
+	assert.True(t, errors.Is(err, httpclient.ErrUnknownSigner))
+}
+
+func TestOAuth2(t *testing.T) {
+	tokens := int64(0)
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		id, secret, _ := request.BasicAuth()
+		if id != "client" || secret != "secret" || request.PostFormValue("grant_type") != "client_credentials" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(writer, `{"access_token":"token-%d-%s","token_type":"bearer","expires_in":1}`,
+			atomic.AddInt64(&tokens, 1), request.PostFormValue("scope"))
+	}))
+	defer tokenServer.Close()
+	revoked := int32(0)
+	initAuthClient(t, http.MethodGet, func(request *http.Request) int {
+		if atomic.LoadInt32(&revoked) == 1 && request.Header.Get("Authorization") == "Bearer token-2-a b" {
+			return http.StatusUnauthorized
+		}
+		return http.StatusOK
+	}, map[string]interface{}{"type": "oauth2", "tokenUrl": tokenServer.URL, "clientId": "client",
+		"clientSecret": "secret", "scopes": []interface{}{"a", "b"}})
+
+	// the token is cached until it is about to expire
+	for i := 0; i < 3; i++ {
+		authorization, _, err := requestAuth(httpclient.NewRequest("auth"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer token-1-a b", authorization)
+	}
+	time.Sleep(600 * time.Millisecond)
+	authorization, _, err := requestAuth(httpclient.NewRequest("auth"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token-2-a b", authorization)
+
+	// and fetched again once the upstream rejects it
+	atomic.StoreInt32(&revoked, 1)
+	_, status, err := requestAuth(httpclient.NewRequest("auth"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	authorization, status, err = requestAuth(httpclient.NewRequest("auth"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Bearer token-3-a b", authorization)
+}
+
+func TestHMAC(t *testing.T) {
+	initAuthClient(t, http.MethodPost, func(request *http.Request) int {
+		body, _ := ioutil.ReadAll(request.Body)
+		hash := sha256.Sum256(body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		_, _ = mac.Write([]byte(strings.Join([]string{http.MethodPost, request.URL.EscapedPath(),
+			request.URL.Query().Encode(), request.Header.Get("X-Timestamp"), hex.EncodeToString(hash[:])}, "\n")))
+		expected := "HMAC-SHA256 keyId=key, signature=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if request.Header.Get("Authorization") != expected {
+			return http.StatusUnauthorized
+		}
+		return http.StatusOK
+	}, map[string]interface{}{"type": "hmac", "keyId": "key", "secret": "secret"})
+
+	_, status, err := requestAuth(httpclient.NewRequest("auth").SetPath("/counters/{key}").
+		SetPathParam("key", "a b").SetQueryParams(map[string]string{"z": "1", "a": "2"}).SetJSONBody(1))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+type signer struct{}
+
+func (signer) Sign(request *httpclient.Request) error {
+	request.SetHeaderParam("Authorization", "Custom "+request.Method())
+	return nil
+}
+
+func TestRegisterSigner(t *testing.T) {
+	httpclient.RegisterSigner("custom", func(map[string]interface{}) (httpclient.Signer, error) {
+		return signer{}, nil
+	})
+	initAuthClient(t, http.MethodPut, ok, map[string]interface{}{"type": "custom"})
+	authorization, _, err := requestAuth(httpclient.NewRequest("auth"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Custom PUT", authorization)
+}
@@ -0,0 +1,367 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/cache"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheConfig is the configuration for caching the responses of a request
+type CacheConfig struct {
+	Enabled bool `json:"enabled"`
+	// Size is the number of responses kept, the least recently used ones are evicted first
+	Size int `json:"size"`
+	// TTL is how long a response stays fresh when the upstream does not say so itself
+	TTL time.Duration `json:"ttl"`
+	// StaleWhileRevalidate is how long a stale response is served while it is refreshed in the background
+	StaleWhileRevalidate time.Duration `json:"staleWhileRevalidate"`
+	// StaleIfError is how long a stale response is served when the upstream fails
+	StaleIfError time.Duration `json:"staleIfError"`
+}
+
+// cachedResponse is a response along with how long it can be served
+type cachedResponse struct {
+	StatusCode           int               `json:"statusCode"`
+	Header               http.Header       `json:"header"`
+	Body                 []byte            `json:"body"`
+	Vary                 map[string]string `json:"vary,omitempty"`
+	ExpiresAt            time.Time         `json:"expiresAt"`
+	StaleWhileRevalidate time.Duration     `json:"staleWhileRevalidate"`
+	StaleIfError         time.Duration     `json:"staleIfError"`
+	// status is how the response was got, sent back in the cache status header
+	status string
+}
+
+// responseCache caches the responses of a request, honouring the cache control of the upstream
+type responseCache struct {
+	name    string
+	config  CacheConfig
+	store   cache.Cache
+	flights *flightGroup
+}
+
+func newResponseCache(name string, config CacheConfig) *responseCache {
+	size := config.Size
+	if size <= 0 {
+		size = constants.DefaultHTTPCacheSize
+	}
+	// the responses are expired here rather than by the store, as the stale ones are still served at times
+	return &responseCache{name: name, config: config, store: cache.NewLRU(size, 0), flights: newFlightGroup()}
+}
+
+// isCacheable reports whether the responses of the request can be cached, only the safe requests without a body are
+func isCacheable(config *RequestConfig, request *Request) bool {
+	switch config.method {
+	case "", http.MethodGet, http.MethodHead:
+		return request.body == nil
+	default:
+		return false
+	}
+}
+
+func getCacheKey(config *RequestConfig, request *Request) string {
+	u := request.url
+	if u == "" {
+		u = config.url
+	}
+	query := make(url.Values, len(request.query))
+	for param, value := range request.query {
+		query.Set(param, value)
+	}
+	return fmt.Sprintf("%s %s %s?%s", request.name, config.method, u, query.Encode())
+}
+
+// getFlightKey is used to get the key the fetches are coalesced on, the cache key along with the request headers
+// The response can vary on any of the request headers, which is only known once it is got, so only the fetches made
+// with the same headers share it.
+func getFlightKey(key string, request *Request) string {
+	if len(request.headers) == 0 {
+		return key
+	}
+	header := make(url.Values, len(request.headers))
+	for name, value := range request.headers {
+		header.Set(name, value)
+	}
+	return key + " " + header.Encode()
+}
+
+func (c *responseCache) get(config *RequestConfig, request *Request,
+	do Handler) (*http.Response, error) {
+	ctx := request.getContext()
+	key := getCacheKey(config, request)
+	entry := c.load(ctx, key, request)
+	now := time.Now()
+	switch {
+	case entry == nil:
+	case entry.isFresh(now):
+		return c.respond(entry, constants.HitCacheStatus), nil
+	case entry.canServeWhileRevalidating(now):
+		// the response is built before the revalidation gets to change the entry
+		response := c.respond(entry, constants.StaleCacheStatus)
+		// the revalidation outlives the caller, keeping its request id and trace headers with a timeout of its own
+		revalidateCtx, cancel := context.WithTimeout(valuesContext{Context: context.Background(), values: ctx},
+			constants.CacheRevalidationTimeout)
+		go func(request *Request) {
+			defer cancel()
+			c.revalidate(key, request, entry, do)
+		}(request.clone(revalidateCtx))
+		return response, nil
+	}
+
+	fetched, err := c.flights.do(ctx, getFlightKey(key, request), func(ctx context.Context) (*cachedResponse, error) {
+		return c.fetch(key, request.clone(ctx), entry, do)
+	})
+	if (err != nil || fetched.StatusCode >= http.StatusInternalServerError) && entry != nil &&
+		entry.canServeOnError(now) {
+		log.Warn(ctx).Err(err).Msgf("serving stale response of %s as the upstream failed", c.name)
+		return c.respond(entry, constants.StaleCacheStatus), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.respond(fetched, fetched.status), nil
+}
+
+func (c *responseCache) revalidate(key string, request *Request, entry *cachedResponse,
+	do Handler) {
+	_, err := c.flights.do(request.getContext(), getFlightKey(key, request),
+		func(ctx context.Context) (*cachedResponse, error) {
+			return c.fetch(key, request.clone(ctx), entry, do)
+		})
+	if err != nil {
+		log.Warn(request.getContext()).Err(err).Msgf("error revalidating cached response of %s", c.name)
+	}
+}
+
+// fetch is used to get the response from the upstream, revalidating the entry when there is one
+func (c *responseCache) fetch(key string, request *Request, entry *cachedResponse,
+	do Handler) (*cachedResponse, error) {
+	ctx := request.getContext()
+	if entry != nil {
+		request = request.clone(ctx)
+		if etag := entry.Header.Get(constants.ETagHeader); etag != "" {
+			request.SetHeaderParam(constants.IfNoneMatchHeader, etag)
+		}
+		if lastModified := entry.Header.Get(constants.LastModifiedHeader); lastModified != "" {
+			request.SetHeaderParam(constants.IfModifiedSinceHeader, lastModified)
+		}
+	}
+	response, err := do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if response.StatusCode == http.StatusNotModified && entry != nil {
+		// the headers of the not modified response replace the stored ones while the body is kept
+		for name, values := range response.Header {
+			entry.Header[name] = values
+		}
+		entry.status = constants.RevalidatedCacheStatus
+		if entry.setFreshness(c.config, now) {
+			c.save(ctx, key, entry)
+		} else {
+			_ = c.store.Delete(ctx, key)
+		}
+		return entry, nil
+	}
+
+	fetched := &cachedResponse{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       body,
+		status:     constants.MissCacheStatus,
+	}
+	if response.StatusCode != http.StatusOK || !fetched.setFreshness(c.config, now) {
+		return fetched, nil
+	}
+	if vary, ok := getVary(response.Header, request); ok {
+		fetched.Vary = vary
+		c.save(ctx, key, fetched)
+	}
+	return fetched, nil
+}
+
+func (c *responseCache) load(ctx context.Context, key string, request *Request) *cachedResponse {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+	var entry cachedResponse
+	if err = json.Unmarshal(data, &entry); err != nil {
+		log.Error(ctx).Err(err).Msgf("error decoding cached response of %s", c.name)
+		return nil
+	}
+	// a response varying on the request headers is only served for the same values of those headers
+	for name, value := range entry.Vary {
+		if request.headers[name] != value {
+			return nil
+		}
+	}
+	return &entry
+}
+
+func (c *responseCache) save(ctx context.Context, key string, entry *cachedResponse) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = c.store.Set(ctx, key, data)
+	}
+	if err != nil {
+		log.Error(ctx).Err(err).Msgf("error caching response of %s", c.name)
+	}
+}
+
+// respond is used to build a new response from the cached one, so that every caller can read the body
+func (c *responseCache) respond(entry *cachedResponse, status string) *http.Response {
+	metrics.GetCounter(fmt.Sprintf(constants.HTTPCacheMetric, c.name, strings.ToLower(status))).Inc()
+	header := entry.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set(constants.CacheStatusHeader, status)
+	return newResponse(entry.StatusCode, header, entry.Body)
+}
+
+// newResponse is used to build a response which was not got from the upstream
+func newResponse(statusCode int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+// setFreshness is used to work out how long the response can be served from its cache control,
+// falling back to the configuration, it reports whether the response can be stored at all
+func (r *cachedResponse) setFreshness(config CacheConfig, now time.Time) bool {
+	directives := parseCacheControl(r.Header.Values(constants.CacheControlHeader))
+	if _, ok := directives[constants.NoStoreDirective]; ok {
+		return false
+	}
+	// the cache is shared by all the callers
+	if _, ok := directives[constants.PrivateDirective]; ok {
+		return false
+	}
+
+	ttl := config.TTL
+	if maxAge, ok := getSeconds(directives, constants.SharedMaxAgeDirective); ok {
+		ttl = maxAge
+	} else if maxAge, ok = getSeconds(directives, constants.MaxAgeDirective); ok {
+		ttl = maxAge
+	} else if expires, err := http.ParseTime(r.Header.Get(constants.ExpiresHeader)); err == nil {
+		ttl = expires.Sub(now)
+	}
+	if _, ok := directives[constants.NoCacheDirective]; ok {
+		ttl = 0
+	}
+
+	staleWhileRevalidate, ok := getSeconds(directives, constants.StaleWhileRevalidateDirective)
+	if !ok {
+		staleWhileRevalidate = config.StaleWhileRevalidate
+	}
+	staleIfError, ok := getSeconds(directives, constants.StaleIfErrorDirective)
+	if !ok {
+		staleIfError = config.StaleIfError
+	}
+	_, mustRevalidate := directives[constants.MustRevalidateDirective]
+	_, proxyRevalidate := directives[constants.ProxyRevalidateDirective]
+	if mustRevalidate || proxyRevalidate {
+		staleWhileRevalidate, staleIfError = 0, 0
+	}
+
+	r.ExpiresAt = now.Add(ttl)
+	r.StaleWhileRevalidate = staleWhileRevalidate
+	r.StaleIfError = staleIfError
+	return true
+}
+
+func (r *cachedResponse) isFresh(now time.Time) bool {
+	return now.Before(r.ExpiresAt)
+}
+
+func (r *cachedResponse) canServeWhileRevalidating(now time.Time) bool {
+	return now.Before(r.ExpiresAt.Add(r.StaleWhileRevalidate))
+}
+
+func (r *cachedResponse) canServeOnError(now time.Time) bool {
+	return now.Before(r.ExpiresAt.Add(r.StaleIfError))
+}
+
+// getVary is used to get the values of the request headers the response varies on,
+// it reports false when the response varies on everything and cannot be cached
+func getVary(header http.Header, request *Request) (map[string]string, bool) {
+	var vary map[string]string
+	for _, value := range header.Values(constants.VaryHeader) {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			switch name {
+			case "":
+				continue
+			case "*":
+				return nil, false
+			}
+			if vary == nil {
+				vary = make(map[string]string)
+			}
+			name = http.CanonicalHeaderKey(name)
+			vary[name] = request.headers[name]
+		}
+	}
+	return vary, true
+}
+
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			name, argument := directive, ""
+			if i := strings.Index(directive, "="); i >= 0 {
+				name, argument = directive[:i], strings.Trim(directive[i+1:], `"`)
+			}
+			directives[strings.ToLower(strings.TrimSpace(name))] = argument
+		}
+	}
+	return directives
+}
+
+func getSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	argument, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(argument)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,287 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// upstream counts the calls made to it and responds with the handler set
+type upstream struct {
+	calls   int64
+	handler http.HandlerFunc
+}
+
+func (u *upstream) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	atomic.AddInt64(&u.calls, 1)
+	u.handler(writer, request)
+}
+
+func (u *upstream) getCalls() int64 {
+	return atomic.LoadInt64(&u.calls)
+}
+
+func initCachedClient(t *testing.T, handler http.HandlerFunc, cache map[string]interface{}) *upstream {
+	u := &upstream{handler: handler}
+	server := httptest.NewServer(u)
+	t.Cleanup(server.Close)
+	cache["enabled"] = true
+	httpclient.Init(httpclient.NewRequestConfig("upstream", map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+		"cache":           cache,
+	}))
+	return u
+}
+
+func request(t *testing.T) (string, string, int) {
+	response, err := httpclient.Get().Request(httpclient.NewRequest("upstream").SetQueryParam("a", "b"))
+	assert.NoError(t, err)
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	return string(body), response.Header.Get(constants.CacheStatusHeader), response.StatusCode
+}
+
+func TestCachedResponse(t *testing.T) {
+	u := initCachedClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set(constants.CacheControlHeader, "max-age=60")
+		_, _ = writer.Write([]byte("cached"))
+	}, map[string]interface{}{})
+
+	body, status, _ := request(t)
+	assert.Equal(t, "cached", body)
+	assert.Equal(t, constants.MissCacheStatus, status)
+	body, status, _ = request(t)
+	assert.Equal(t, "cached", body)
+	assert.Equal(t, constants.HitCacheStatus, status)
+	assert.Equal(t, int64(1), u.getCalls())
+}
+
+func TestNoStore(t *testing.T) {
+	u := initCachedClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set(constants.CacheControlHeader, "no-store")
+		_, _ = writer.Write([]byte("fresh"))
+	}, map[string]interface{}{"ttlInMillis": 60000})
+
+	request(t)
+	_, status, _ := request(t)
+	assert.Equal(t, constants.MissCacheStatus, status)
+	assert.Equal(t, int64(2), u.getCalls())
+}
+
+func TestRevalidation(t *testing.T) {
+	u := initCachedClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set(constants.CacheControlHeader, "no-cache")
+		writer.Header().Set(constants.ETagHeader, `"v1"`)
+		if request.Header.Get(constants.IfNoneMatchHeader) == `"v1"` {
+			writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = writer.Write([]byte("validated"))
+	}, map[string]interface{}{})
+
+	request(t)
+	body, status, code := request(t)
+	assert.Equal(t, "validated", body)
+	assert.Equal(t, constants.RevalidatedCacheStatus, status)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(2), u.getCalls())
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	version := int64(0)
+	initCachedClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set(constants.CacheControlHeader, "max-age=0, stale-while-revalidate=60")
+		if atomic.AddInt64(&version, 1) == 1 {
+			_, _ = writer.Write([]byte("old"))
+			return
+		}
+		_, _ = writer.Write([]byte("new"))
+	}, map[string]interface{}{})
+
+	request(t)
+	body, status, _ := request(t)
+	assert.Equal(t, "old", body)
+	assert.Equal(t, constants.StaleCacheStatus, status)
+	assert.Eventually(t, func() bool {
+		body, _, _ = request(t)
+		return body == "new"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStaleWhileRevalidatePropagation(t *testing.T) {
+	revalidated := make(chan http.Header, 1)
+	version := int64(0)
+	initCachedClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set(constants.CacheControlHeader, "max-age=0, stale-while-revalidate=60")
+		if atomic.AddInt64(&version, 1) > 1 {
+			revalidated <- request.Header
+		}
+	}, map[string]interface{}{})
+
+	request(t)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set("id", "request-1")
+	ctx.Set(constants.TraceHeadersKey, httpclient.GetTraceHeaders(http.Header{
+		"Traceparent": []string{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+	}))
+	response, err := httpclient.Get().Request(httpclient.NewRequest("upstream").SetContext(ctx).
+		SetQueryParam("a", "b"))
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, constants.StaleCacheStatus, response.Header.Get(constants.CacheStatusHeader))
+
+	// the revalidation made after the caller got the stale response keeps its request id and trace headers
+	select {
+	case header := <-revalidated:
+		assert.Equal(t, "request-1", header.Get("X-requestId"))
+		assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", header.Get("traceparent"))
+	case <-time.After(time.Second):
+		assert.Fail(t, "the stale response was not revalidated")
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	failing := int32(0)
+	handler := func(writer http.ResponseWriter, _ *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = writer.Write([]byte("last good"))
+	}
+
+	initCachedClient(t, handler, map[string]interface{}{"staleIfErrorInMillis": 60000})
+	request(t)
+	atomic.StoreInt32(&failing, 1)
+	body, status, code := request(t)
+	assert.Equal(t, "last good", body)
+	assert.Equal(t, constants.StaleCacheStatus, status)
+	assert.Equal(t, http.StatusOK, code)
+
+	// without stale if error allowed the failure reaches the caller
+	atomic.StoreInt32(&failing, 0)
+	initCachedClient(t, handler, map[string]interface{}{})
+	request(t)
+	atomic.StoreInt32(&failing, 1)
+	_, _, code = request(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestSingleFlight(t *testing.T) {
+	release := make(chan struct{})
+	u := initCachedClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+		<-release
+		writer.Header().Set(constants.CacheControlHeader, "max-age=60")
+		_, _ = writer.Write([]byte("shared"))
+	}, map[string]interface{}{})
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i], _, _ = request(t)
+		}(i)
+	}
+	assert.Eventually(t, func() bool {
+		return u.getCalls() == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"shared", "shared", "shared", "shared", "shared"}, bodies)
+	assert.Equal(t, int64(1), u.getCalls())
+}
+
+func TestSingleFlightCallerGoesAway(t *testing.T) {
+	release := make(chan struct{})
+	u := initCachedClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+		<-release
+		writer.Header().Set(constants.CacheControlHeader, "max-age=60")
+		_, _ = writer.Write([]byte("shared"))
+	}, map[string]interface{}{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := httpclient.Get().Request(httpclient.NewRequest("upstream").SetContext(ctx).SetQueryParam("a", "b"))
+		first <- err
+	}()
+	assert.Eventually(t, func() bool {
+		return u.getCalls() == 1
+	}, time.Second, time.Millisecond)
+	second := make(chan string)
+	go func() {
+		body, _, _ := request(t)
+		second <- body
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the caller which started the fetch going away fails neither the fetch nor the caller waiting for it
+	cancel()
+	assert.True(t, errors.Is(<-first, context.Canceled))
+	close(release)
+	assert.Equal(t, "shared", <-second)
+	assert.Equal(t, int64(1), u.getCalls())
+}
+
+func TestSingleFlightVary(t *testing.T) {
+	release := make(chan struct{})
+	u := initCachedClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		<-release
+		writer.Header().Set(constants.CacheControlHeader, "max-age=60")
+		writer.Header().Set(constants.VaryHeader, "Accept-Language")
+		_, _ = writer.Write([]byte(request.Header.Get("Accept-Language")))
+	}, map[string]interface{}{})
+
+	var wg sync.WaitGroup
+	languages := []string{"en", "fr"}
+	bodies := make([]string, len(languages))
+	for i, language := range languages {
+		wg.Add(1)
+		go func(i int, language string) {
+			defer wg.Done()
+			response, err := httpclient.Get().Request(httpclient.NewRequest("upstream").SetQueryParam("a", "b").
+				SetHeaderParam("Accept-Language", language))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer func() {
+				_ = response.Body.Close()
+			}()
+			body, _ := ioutil.ReadAll(response.Body)
+			bodies[i] = string(body)
+		}(i, language)
+	}
+	// the fetches for the different values of the headers the response can vary on are not coalesced
+	assert.Eventually(t, func() bool {
+		return u.getCalls() == 2
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, languages, bodies)
+}
+
+func TestUnknownRequest(t *testing.T) {
+	httpclient.Init()
+	_, err := httpclient.Get().Request(httpclient.NewRequest("unknown"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,245 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/afex/hystrix-go/hystrix"
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"github.com/afex/hystrix-go/hystrix/rolling"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Circuit is the state of the circuit breaker of a request along with its recent traffic
+// The counts are over the rolling window of the circuit breaker.
+type Circuit struct {
+	Name               string     `json:"name"`
+	State              string     `json:"state"`
+	ErrorPercentage    int        `json:"errorPercentage"`
+	Requests           int64      `json:"requests"`
+	ConcurrentRequests int64      `json:"concurrentRequests"`
+	Rejections         int64      `json:"rejections"`
+	Timeouts           int64      `json:"timeouts"`
+	LastError          string     `json:"lastError,omitempty"`
+	LastErrorAt        *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+var (
+	// ErrUnknownRequest is returned for a request name which is not configured
+	ErrUnknownRequest = errors.New("no http request configured")
+	// ErrCircuitForcedOpen is returned for the requests rejected while their circuit is forced open
+	ErrCircuitForcedOpen = errors.New("circuit forced open")
+)
+
+// upstream is what is tracked of a request across the initialisations of the client
+type upstream struct {
+	inFlight    int64
+	forcedOpen  int32
+	mu          sync.RWMutex
+	lastError   string
+	lastErrorAt time.Time
+	// latencies of the successful attempts, along with the requests and the retries, for the attempts made
+	latencies *rolling.Timing
+	requests  *rolling.Number
+	retries   *rolling.Number
+}
+
+// circuitCollector collects the metrics hystrix reports for a circuit
+type circuitCollector struct {
+	mu         sync.RWMutex
+	attempts   *rolling.Number
+	errors     *rolling.Number
+	rejections *rolling.Number
+	timeouts   *rolling.Number
+}
+
+var (
+	upstreamsMu sync.Mutex
+	upstreams   = make(map[string]*upstream)
+
+	collectorsMu sync.RWMutex
+	collectors   = make(map[string]*circuitCollector)
+)
+
+func init() {
+	metricCollector.Registry.Register(newCircuitCollector)
+}
+
+// GetCircuits is used to get the circuits of all the configured requests
+func GetCircuits() []Circuit {
+	c := getClient()
+	names := make([]string, 0, len(c.configs))
+	for name := range c.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	circuits := make([]Circuit, 0, len(names))
+	for _, name := range names {
+		circuits = append(circuits, getCircuit(c.configs[name]))
+	}
+	return circuits
+}
+
+// GetCircuit is used to get the circuit of the request
+func GetCircuit(name string) (Circuit, error) {
+	config, ok := getClient().configs[name]
+	if !ok {
+		return Circuit{}, fmt.Errorf("%w with name %s", ErrUnknownRequest, name)
+	}
+	return getCircuit(config), nil
+}
+
+// ForceOpenCircuit is used to reject all the requests until the circuit is reset
+func ForceOpenCircuit(ctx context.Context, name string) (Circuit, error) {
+	if _, ok := getClient().configs[name]; !ok {
+		return Circuit{}, fmt.Errorf("%w with name %s", ErrUnknownRequest, name)
+	}
+	atomic.StoreInt32(&getUpstream(name).forcedOpen, 1)
+	log.Warn(ctx).Str(constants.RequestNameKey, name).Msg("circuit forced open")
+	return GetCircuit(name)
+}
+
+// ResetCircuit is used to close the circuit, whether it was forced open or opened by the failures
+func ResetCircuit(ctx context.Context, name string) (Circuit, error) {
+	config, ok := getClient().configs[name]
+	if !ok {
+		return Circuit{}, fmt.Errorf("%w with name %s", ErrUnknownRequest, name)
+	}
+	atomic.StoreInt32(&getUpstream(name).forcedOpen, 0)
+	if hasCircuit(config) {
+		// hystrix closes an open circuit on a success, starting its metrics afresh
+		circuit, _, err := hystrix.GetCircuit(name)
+		if err != nil {
+			return Circuit{}, err
+		}
+		if err = circuit.ReportEvent([]string{constants.SuccessCircuitEvent}, time.Now(), 0); err != nil {
+			return Circuit{}, err
+		}
+	}
+	log.Warn(ctx).Str(constants.RequestNameKey, name).Msg("circuit reset")
+	return GetCircuit(name)
+}
+
+func getClient() *client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return instance
+}
+
+func hasCircuit(config *RequestConfig) bool {
+	return getMap(config.configs, constants.HTTPHystrixConfigKey) != nil
+}
+
+func getCircuit(config *RequestConfig) Circuit {
+	u := getUpstream(config.name)
+	circuit := Circuit{
+		Name:               config.name,
+		State:              constants.NoCircuitState,
+		ConcurrentRequests: atomic.LoadInt64(&u.inFlight),
+	}
+	u.mu.RLock()
+	if u.lastError != "" {
+		lastErrorAt := u.lastErrorAt
+		circuit.LastError, circuit.LastErrorAt = u.lastError, &lastErrorAt
+	}
+	u.mu.RUnlock()
+
+	if hasCircuit(config) {
+		circuit.State = constants.ClosedCircuitState
+		if breaker, _, err := hystrix.GetCircuit(config.name); err == nil && breaker.IsOpen() {
+			circuit.State = constants.OpenCircuitState
+		}
+		collectorsMu.RLock()
+		collector, ok := collectors[config.name]
+		collectorsMu.RUnlock()
+		if ok {
+			collector.fill(&circuit)
+		}
+	}
+	if atomic.LoadInt32(&u.forcedOpen) == 1 {
+		circuit.State = constants.ForcedOpenCircuitState
+	}
+	return circuit
+}
+
+func getUpstream(name string) *upstream {
+	upstreamsMu.Lock()
+	defer upstreamsMu.Unlock()
+	u, ok := upstreams[name]
+	if !ok {
+		u = &upstream{latencies: rolling.NewTiming(), requests: rolling.NewNumber(), retries: rolling.NewNumber()}
+		upstreams[name] = u
+	}
+	return u
+}
+
+// guard rejects the requests while their circuit is forced open and tracks the requests in flight and the failures
+func guard(config *RequestConfig, next Handler) Handler {
+	u := getUpstream(config.name)
+	return func(request *Request) (*http.Response, error) {
+		if atomic.LoadInt32(&u.forcedOpen) == 1 {
+			return nil, fmt.Errorf("%w for %s", ErrCircuitForcedOpen, config.name)
+		}
+		atomic.AddInt64(&u.inFlight, 1)
+		response, err := next(request)
+		atomic.AddInt64(&u.inFlight, -1)
+		switch {
+		case err != nil:
+			u.setLastError(err.Error())
+		case response.StatusCode >= http.StatusInternalServerError:
+			u.setLastError(fmt.Sprintf("upstream responded with status %d", response.StatusCode))
+		}
+		return response, err
+	}
+}
+
+func (u *upstream) setLastError(lastError string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.lastError, u.lastErrorAt = lastError, time.Now()
+}
+
+func newCircuitCollector(name string) metricCollector.MetricCollector {
+	c := &circuitCollector{}
+	c.Reset()
+	collectorsMu.Lock()
+	collectors[name] = c
+	collectorsMu.Unlock()
+	return c
+}
+
+func (c *circuitCollector) Update(result metricCollector.MetricResult) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.attempts.Increment(result.Attempts)
+	c.errors.Increment(result.Errors)
+	c.rejections.Increment(result.Rejects + result.ShortCircuits)
+	c.timeouts.Increment(result.Timeouts)
+}
+
+func (c *circuitCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts = rolling.NewNumber()
+	c.errors = rolling.NewNumber()
+	c.rejections = rolling.NewNumber()
+	c.timeouts = rolling.NewNumber()
+}
+
+func (c *circuitCollector) fill(circuit *Circuit) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	circuit.Requests = int64(c.attempts.Sum(now))
+	circuit.Rejections = int64(c.rejections.Sum(now))
+	circuit.Timeouts = int64(c.timeouts.Sum(now))
+	if circuit.Requests > 0 {
+		circuit.ErrorPercentage = int(c.errors.Sum(now) / float64(circuit.Requests) * 100)
+	}
+}
@@ -0,0 +1,86 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuits(t *testing.T) {
+	failing := int32(1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	httpclient.Init(httpclient.NewRequestConfigs(map[string]interface{}{
+		"circuit": map[string]interface{}{
+			"method":          http.MethodGet,
+			"url":             server.URL,
+			"timeoutinmillis": 1000,
+			"retrycount":      0,
+			"hystrixconfig": map[string]interface{}{
+				"hystrixtimeoutinmillis": 1000,
+				"maxconcurrentrequests":  10,
+				"errorpercentthresold":   50,
+				"sleepwindowinmillis":    60000,
+			},
+		},
+		"plain": map[string]interface{}{"method": http.MethodGet, "url": server.URL, "timeoutinmillis": 1000},
+	})...)
+	request := func() error {
+		response, err := httpclient.Get().Request(httpclient.NewRequest("circuit"))
+		if err == nil {
+			_ = response.Body.Close()
+		}
+		return err
+	}
+
+	// the failures open the circuit once there are enough requests
+	assert.Eventually(t, func() bool {
+		_ = request()
+		circuit, err := httpclient.GetCircuit("circuit")
+		return err == nil && circuit.State == constants.OpenCircuitState
+	}, 5*time.Second, 10*time.Millisecond)
+	// the open circuit short circuits the requests, the metrics of which are reported asynchronously
+	assert.Error(t, request())
+	var circuits []httpclient.Circuit
+	assert.Eventually(t, func() bool {
+		circuits = httpclient.GetCircuits()
+		return circuits[0].Rejections > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, circuits, 2)
+	assert.Equal(t, "circuit", circuits[0].Name)
+	assert.GreaterOrEqual(t, circuits[0].ErrorPercentage, 50)
+	assert.NotZero(t, circuits[0].Requests)
+	assert.Contains(t, circuits[0].LastError, "circuit open")
+	assert.NotNil(t, circuits[0].LastErrorAt)
+	assert.Equal(t, httpclient.Circuit{Name: "plain", State: constants.NoCircuitState}, circuits[1])
+
+	atomic.StoreInt32(&failing, 0)
+	circuit, err := httpclient.ResetCircuit(context.Background(), "circuit")
+	assert.NoError(t, err)
+	assert.Equal(t, constants.ClosedCircuitState, circuit.State)
+	assert.NoError(t, request())
+
+	// a forced open circuit rejects the requests until reset
+	circuit, err = httpclient.ForceOpenCircuit(context.Background(), "circuit")
+	assert.NoError(t, err)
+	assert.Equal(t, constants.ForcedOpenCircuitState, circuit.State)
+	assert.True(t, errors.Is(request(), httpclient.ErrCircuitForcedOpen))
+	_, err = httpclient.ResetCircuit(context.Background(), "circuit")
+	assert.NoError(t, err)
+	assert.NoError(t, request())
+
+	_, err = httpclient.ForceOpenCircuit(context.Background(), "unknown")
+	assert.True(t, errors.Is(err, httpclient.ErrUnknownRequest))
+}
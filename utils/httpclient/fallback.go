@@ -0,0 +1,220 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	httpclient "github.com/angel-one/go-http-client"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/cache"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// FallbackConfig is the configuration for the response served in place of the upstream one when the request fails
+// The request fails when it errors, including while its circuit is open, or the upstream responds with a server error.
+type FallbackConfig struct {
+	// Strategy is one of cache, serving the last known good response, static, serving the configured payload,
+	// or url, making the request to the alternate url
+	Strategy string `json:"strategy"`
+	// Size is the number of last known good responses kept, the least recently used ones are evicted first
+	Size        int    `json:"size,omitempty"`
+	StatusCode  int    `json:"statusCode,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// ErrNoFallback is returned when there is no fallback response to be served in place of the failed one
+var ErrNoFallback = errors.New("no fallback response")
+
+// fallback serves the responses of a request when the upstream fails, using the configured strategy
+type fallback struct {
+	name   string
+	config FallbackConfig
+	// store keeps the last known good responses for the cache strategy
+	store cache.Cache
+	// alternate is the request made to the alternate url for the url strategy, without a circuit of its own
+	alternate *RequestConfig
+}
+
+func newFallback(config *RequestConfig) *fallback {
+	f := &fallback{name: config.name, config: config.fallback}
+	switch config.fallback.Strategy {
+	case constants.CacheFallbackStrategy:
+		size := config.fallback.Size
+		if size <= 0 {
+			size = constants.DefaultHTTPCacheSize
+		}
+		f.store = cache.NewLRU(size, 0)
+	case constants.URLFallbackStrategy:
+		f.alternate = newAlternateRequestConfig(config)
+	}
+	return f
+}
+
+// newAlternateRequestConfig is used to create the config of the request to the alternate url
+// It keeps the timeouts, the retries and the auth of the request, while the circuit of the request would reject it.
+func newAlternateRequestConfig(config *RequestConfig) *RequestConfig {
+	name := config.name + constants.FallbackRequestSuffix
+	configs := make(map[string]interface{}, len(config.configs))
+	for key, value := range config.configs {
+		switch {
+		case strings.EqualFold(key, constants.HTTPHystrixConfigKey),
+			strings.EqualFold(key, constants.HTTPCacheConfigKey),
+			strings.EqualFold(key, constants.HTTPFallbackConfigKey):
+		case strings.EqualFold(key, constants.HTTPURLConfigKey):
+			configs[key] = config.fallback.URL
+		default:
+			configs[key] = value
+		}
+	}
+	return &RequestConfig{
+		name:    name,
+		method:  config.method,
+		url:     config.fallback.URL,
+		auth:    config.auth,
+		config:  httpclient.NewRequestConfig(name, configs),
+		configs: configs,
+	}
+}
+
+// getFallbackKey is used to get the key of the last known good response of the request, the same as its cache key
+func getFallbackKey(config *RequestConfig, request *Request) string {
+	r := request.clone(request.getContext())
+	r.url, _ = request.getURL(config)
+	return getCacheKey(config, r)
+}
+
+// get is used to make the request, serving the fallback response in its place when it fails and it is idempotent
+func (f *fallback) get(config *RequestConfig, request *Request, do, alternate Handler) (*http.Response, error) {
+	ctx := request.getContext()
+	response, err := do(request)
+	if err == nil && response.StatusCode < http.StatusInternalServerError {
+		if f.store != nil && response.StatusCode == http.StatusOK && isCacheable(config, request) {
+			return f.remember(ctx, getFallbackKey(config, request), response)
+		}
+		return response, nil
+	}
+	// the upstream may have acted on the failed requests which are not idempotent, so they are not served in place
+	// of nor made again to the alternate url, the method being the one of the config unless the request has its own
+	method := request.method
+	if method == "" {
+		method = config.method
+	}
+	if !isIdempotent(method) {
+		return response, err
+	}
+
+	served, fallbackErr := f.serve(config, request, alternate)
+	if fallbackErr != nil {
+		log.Warn(ctx).Err(fallbackErr).Str(constants.RequestNameKey, f.name).
+			Msg("unable to serve fallback response as the upstream failed")
+		return response, err
+	}
+	if response != nil {
+		_ = response.Body.Close()
+	}
+	log.Warn(ctx).Err(err).Str(constants.RequestNameKey, f.name).
+		Msgf("serving %s fallback response as the upstream failed", f.config.Strategy)
+	metrics.GetCounter(fmt.Sprintf(constants.HTTPFallbackMetric, f.name, f.config.Strategy)).Inc()
+	served.Header.Set(constants.FallbackHeader, f.config.Strategy)
+	return served, nil
+}
+
+func (f *fallback) serve(config *RequestConfig, request *Request, alternate Handler) (*http.Response, error) {
+	switch f.config.Strategy {
+	case constants.CacheFallbackStrategy:
+		return f.load(request.getContext(), getFallbackKey(config, request))
+	case constants.StaticFallbackStrategy:
+		return f.static(), nil
+	case constants.URLFallbackStrategy:
+		return f.request(request, alternate)
+	default:
+		return nil, fmt.Errorf("%w for %s with the unknown strategy %s", ErrNoFallback, f.name, f.config.Strategy)
+	}
+}
+
+// remember is used to keep the good response to be served later, reading its body and replacing it for the caller
+func (f *fallback) remember(ctx context.Context, key string, response *http.Response) (*http.Response, error) {
+	body, err := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	header := response.Header.Clone()
+	// the response could have been served by the cache, which is not what it is going to be served by
+	header.Del(constants.CacheStatusHeader)
+	data, err := json.Marshal(cachedResponse{StatusCode: response.StatusCode, Header: header, Body: body})
+	if err == nil {
+		err = f.store.Set(ctx, key, data)
+	}
+	if err != nil {
+		log.Error(ctx).Err(err).Msgf("error keeping last known good response of %s", f.name)
+	}
+	return response, nil
+}
+
+func (f *fallback) load(ctx context.Context, key string) (*http.Response, error) {
+	data, ok, err := f.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w for %s as there is no last known good response", ErrNoFallback, f.name)
+	}
+	var entry cachedResponse
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return newResponse(entry.StatusCode, entry.Header.Clone(), entry.Body), nil
+}
+
+func (f *fallback) static() *http.Response {
+	statusCode := f.config.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	contentType := f.config.ContentType
+	if contentType == "" {
+		contentType = constants.JSONContentType
+	}
+	header := make(http.Header)
+	header.Set(constants.ContentTypeHeader, contentType)
+	return newResponse(statusCode, header, []byte(f.config.Body))
+}
+
+// request is used to make the request to the alternate url, with the path, params, headers and body of the request
+func (f *fallback) request(request *Request, alternate Handler) (*http.Response, error) {
+	r := request.clone(request.getContext())
+	r.name, r.url = f.alternate.name, f.alternate.url
+	u, err := r.getURL(f.alternate)
+	if err != nil {
+		return nil, err
+	}
+	r.url, r.path = u, ""
+	// the body was read by the failed request
+	if seeker, ok := r.body.(io.Seeker); ok {
+		if _, err = seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	response, err := alternate(r)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusInternalServerError {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("%w for %s as the alternate url responded with status %d",
+			ErrNoFallback, f.name, response.StatusCode)
+	}
+	return response, nil
+}
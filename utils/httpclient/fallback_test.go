@@ -0,0 +1,134 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func initFallbackClient(t *testing.T, handler http.HandlerFunc, fallback map[string]interface{}) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	httpclient.Init(httpclient.NewRequestConfig("fallback", map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+		"fallback":        fallback,
+	}))
+}
+
+func requestFallback(t *testing.T, request *httpclient.Request) (string, string, int) {
+	response, err := httpclient.Get().Request(request)
+	if !assert.NoError(t, err) {
+		return "", "", 0
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	return string(body), response.Header.Get(constants.FallbackHeader), response.StatusCode
+}
+
+func TestCacheFallback(t *testing.T) {
+	initFallbackClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("good " + request.URL.Query().Get("a")))
+	}, map[string]interface{}{"strategy": "cache"})
+
+	body, fallback, _ := requestFallback(t, httpclient.NewRequest("fallback").SetQueryParam("a", "b"))
+	assert.Equal(t, "good b", body)
+	assert.Empty(t, fallback)
+
+	// the last known good response is served while the circuit is open
+	_, err := httpclient.ForceOpenCircuit(context.Background(), "fallback")
+	assert.NoError(t, err)
+	defer func() {
+		_, _ = httpclient.ResetCircuit(context.Background(), "fallback")
+	}()
+	body, fallback, status := requestFallback(t, httpclient.NewRequest("fallback").SetQueryParam("a", "b"))
+	assert.Equal(t, "good b", body)
+	assert.Equal(t, constants.CacheFallbackStrategy, fallback)
+	assert.Equal(t, http.StatusOK, status)
+
+	// there is nothing to serve for a request never made successfully
+	_, err = httpclient.Get().Request(httpclient.NewRequest("fallback").SetQueryParam("a", "c"))
+	assert.True(t, errors.Is(err, httpclient.ErrCircuitForcedOpen))
+}
+
+func TestStaticFallback(t *testing.T) {
+	initFallbackClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}, map[string]interface{}{"strategy": "static", "body": `{"message":"default"}`})
+
+	response, err := httpclient.Get().Request(httpclient.NewRequest("fallback"))
+	assert.NoError(t, err)
+	assert.Equal(t, constants.JSONContentType, response.Header.Get(constants.ContentTypeHeader))
+	_ = response.Body.Close()
+	body, fallback, status := requestFallback(t, httpclient.NewRequest("fallback"))
+	assert.Equal(t, `{"message":"default"}`, body)
+	assert.Equal(t, constants.StaticFallbackStrategy, fallback)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestURLFallback(t *testing.T) {
+	alternateCalls := int64(0)
+	alternate := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt64(&alternateCalls, 1)
+		body, _ := ioutil.ReadAll(request.Body)
+		_, _ = writer.Write([]byte(request.URL.Path + " " + string(body)))
+	}))
+	defer alternate.Close()
+	initFallbackClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}, map[string]interface{}{"strategy": "url", "url": alternate.URL + "/backup"})
+
+	body, fallback, _ := requestFallback(t, httpclient.NewRequest("fallback").
+		SetPath("/counters/{key}").SetPathParam("key", "a").SetJSONBody("value"))
+	assert.Equal(t, `/backup/counters/a "value"`, body)
+	assert.Equal(t, constants.URLFallbackStrategy, fallback)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&alternateCalls))
+
+	// the requests which cannot be built are not sent anywhere
+	_, err := httpclient.Get().Request(httpclient.NewRequest("fallback").SetPath("/counters/{key}"))
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&alternateCalls))
+}
+
+func TestFallbackOnlyIdempotent(t *testing.T) {
+	alternateCalls := int64(0)
+	alternate := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt64(&alternateCalls, 1)
+	}))
+	defer alternate.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// the upstream may have acted on the failed request, so its failure is what is returned
+	for _, fallback := range []map[string]interface{}{
+		{"strategy": "static", "body": `{"message":"default"}`},
+		{"strategy": "url", "url": alternate.URL},
+	} {
+		httpclient.Init(httpclient.NewRequestConfig("fallback", map[string]interface{}{
+			"method":          http.MethodPost,
+			"url":             server.URL,
+			"timeoutinmillis": 1000,
+			"retrycount":      0,
+			"fallback":        fallback,
+		}))
+		_, header, status := requestFallback(t, httpclient.NewRequest("fallback").SetJSONBody("value"))
+		assert.Empty(t, header)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	}
+	assert.Equal(t, int64(0), atomic.LoadInt64(&alternateCalls))
+}
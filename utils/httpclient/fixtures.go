@@ -0,0 +1,271 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// FixturesConfig is the configuration for recording the exchanges with the upstreams and replaying them
+type FixturesConfig struct {
+	// Directory holds a fixture file for every request name, named after it
+	Directory string
+	// Mode is one of record, replay, or auto, replaying the requests having a fixture file and recording the others
+	Mode string
+	// Strict fails the requests matching none of the recorded exchanges while replaying, instead of making them
+	Strict bool
+	// Match is the parts of the requests matched against the recorded ones, out of method, path, query and body,
+	// all of them when empty
+	Match []string
+}
+
+// ErrUnmatchedFixture is returned in the strict mode for the requests matching none of the recorded exchanges
+var ErrUnmatchedFixture = errors.New("no recorded exchange matching the request")
+
+// Fixtures records the exchanges with the upstreams into fixture files and replays them
+// Its middleware is added to the client with Use, and it is closed to save the recorded exchanges.
+type Fixtures struct {
+	config   FixturesConfig
+	match    map[string]bool
+	mu       sync.Mutex
+	closed   bool
+	fixtures map[string]*fixtureFile
+}
+
+// fixtureFile is the exchanges of a request, either being replayed or recorded
+type fixtureFile struct {
+	replaying bool
+	recorded  bool
+	Exchanges []*exchange `json:"exchanges"`
+}
+
+type exchange struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+	replayed bool
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// NewFixtures is used to create the fixtures with the config
+// The mode can be overridden by the HTTP_FIXTURES_MODE environment variable, to record the fixtures again.
+func NewFixtures(config FixturesConfig) *Fixtures {
+	if mode, ok := os.LookupEnv(constants.FixturesModeEnv); ok && mode != "" {
+		config.Mode = mode
+	}
+	if config.Mode == "" {
+		config.Mode = constants.AutoFixturesMode
+	}
+	match := make(map[string]bool)
+	for _, part := range config.Match {
+		match[part] = true
+	}
+	if len(match) == 0 {
+		for _, part := range []string{constants.MethodFixtureMatch, constants.PathFixtureMatch,
+			constants.QueryFixtureMatch, constants.BodyFixtureMatch} {
+			match[part] = true
+		}
+	}
+	return &Fixtures{config: config, match: match, fixtures: make(map[string]*fixtureFile)}
+}
+
+// Middleware is used to replay the recorded exchanges for the requests, or record them as they are made
+func (f *Fixtures) Middleware(config *RequestConfig, next Handler) Handler {
+	return func(request *Request) (*http.Response, error) {
+		if f.isClosed() {
+			return next(request)
+		}
+		recorded, err := newRecordedRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		fixture, err := f.getFixture(config.name)
+		if err != nil {
+			return nil, err
+		}
+		if fixture.replaying {
+			if response, ok := f.replay(fixture, recorded); ok {
+				return response, nil
+			}
+			if f.config.Strict {
+				return nil, fmt.Errorf("%w %s %s?%s of the %s request", ErrUnmatchedFixture,
+					recorded.Method, recorded.Path, recorded.Query, config.name)
+			}
+			return next(request)
+		}
+		response, err := next(request)
+		if err != nil {
+			return nil, err
+		}
+		return f.record(fixture, recorded, response)
+	}
+}
+
+// Close is used to save the recorded exchanges into the fixture files, the requests are made as usual afterwards
+func (f *Fixtures) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for name, fixture := range f.fixtures {
+		if !fixture.recorded {
+			continue
+		}
+		data, err := json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(f.config.Directory, 0755); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(f.getPath(name), append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fixtures) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+func (f *Fixtures) getPath(name string) string {
+	return filepath.Join(f.config.Directory, name+constants.FixtureFileExtension)
+}
+
+// getFixture is used to get the fixture of the request, loading it from its file when replaying
+func (f *Fixtures) getFixture(name string) (*fixtureFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fixture, ok := f.fixtures[name]; ok {
+		return fixture, nil
+	}
+	fixture := &fixtureFile{}
+	data, err := ioutil.ReadFile(f.getPath(name))
+	switch {
+	case f.config.Mode == constants.RecordFixturesMode:
+	case err == nil:
+		if err = json.Unmarshal(data, fixture); err != nil {
+			return nil, fmt.Errorf("error decoding the fixture of the %s request: %w", name, err)
+		}
+		fixture.replaying = true
+	case !os.IsNotExist(err):
+		return nil, err
+	case f.config.Mode == constants.ReplayFixturesMode:
+		// there being no fixture, there is nothing to be replayed
+		fixture.replaying = true
+	}
+	f.fixtures[name] = fixture
+	return fixture, nil
+}
+
+// replay is used to get the response of the first recorded exchange matching the request not replayed yet,
+// the last one matching is replayed again once all of them are
+func (f *Fixtures) replay(fixture *fixtureFile, request recordedRequest) (*http.Response, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matched *exchange
+	for _, e := range fixture.Exchanges {
+		if !f.matches(e.Request, request) {
+			continue
+		}
+		matched = e
+		if !e.replayed {
+			break
+		}
+	}
+	if matched == nil {
+		return nil, false
+	}
+	matched.replayed = true
+	response := matched.Response
+	return newResponse(response.StatusCode, response.Header.Clone(), []byte(response.Body)), true
+}
+
+func (f *Fixtures) record(fixture *fixtureFile, request recordedRequest,
+	response *http.Response) (*http.Response, error) {
+	body, err := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fixture.recorded = true
+	fixture.Exchanges = append(fixture.Exchanges, &exchange{
+		Request: request,
+		Response: recordedResponse{
+			StatusCode: response.StatusCode,
+			Header:     response.Header.Clone(),
+			Body:       string(body),
+		},
+	})
+	return response, nil
+}
+
+func (f *Fixtures) matches(recorded, request recordedRequest) bool {
+	return (!f.match[constants.MethodFixtureMatch] || recorded.Method == request.Method) &&
+		(!f.match[constants.PathFixtureMatch] || recorded.Path == request.Path) &&
+		(!f.match[constants.QueryFixtureMatch] || recorded.Query == request.Query) &&
+		(!f.match[constants.BodyFixtureMatch] || bodiesMatch(recorded.Body, request.Body))
+}
+
+// newRecordedRequest is used to get the parts of the request which are matched, reading its body and replacing it
+// The headers are left out, not to have the credentials in the fixtures.
+func newRecordedRequest(request *Request) (recordedRequest, error) {
+	u, err := url.Parse(request.url)
+	if err != nil {
+		return recordedRequest{}, err
+	}
+	query := u.Query()
+	for param, value := range request.query {
+		query.Set(param, value)
+	}
+	recorded := recordedRequest{Method: request.Method(), Path: u.Path, Query: query.Encode()}
+	if recorded.Path == "" {
+		recorded.Path = "/"
+	}
+	if request.body != nil {
+		body, err := ioutil.ReadAll(request.body)
+		if err != nil {
+			return recordedRequest{}, err
+		}
+		request.body = bytes.NewReader(body)
+		recorded.Body = string(body)
+	}
+	return recorded, nil
+}
+
+// bodiesMatch compares the json bodies regardless of their formatting and the order of their keys
+func bodiesMatch(recorded, body string) bool {
+	if recorded == body {
+		return true
+	}
+	var r, b interface{}
+	if json.Unmarshal([]byte(recorded), &r) != nil || json.Unmarshal([]byte(body), &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(r, b)
+}
@@ -0,0 +1,111 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func initFixturesClient(t *testing.T, url string, config httpclient.FixturesConfig) *httpclient.Fixtures {
+	fixtures := httpclient.NewFixtures(config)
+	t.Cleanup(func() {
+		assert.NoError(t, fixtures.Close())
+	})
+	httpclient.Use(fixtures.Middleware)
+	httpclient.Init(httpclient.NewRequestConfig("fixture", map[string]interface{}{
+		"method":          http.MethodPost,
+		"url":             url,
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+	}))
+	return fixtures
+}
+
+func requestFixture(query, body string) (string, error) {
+	response, err := httpclient.Get().Request(httpclient.NewRequest("fixture").SetPath("/echo").
+		SetQueryParam("q", query).SetBody(strings.NewReader(body)))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	b, err := ioutil.ReadAll(response.Body)
+	return string(b), err
+}
+
+func TestFixturesProxied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(request.Method))
+	}))
+	defer server.Close()
+	directory := t.TempDir()
+	fixtures := httpclient.NewFixtures(httpclient.FixturesConfig{Directory: directory})
+	httpclient.Use(fixtures.Middleware)
+	httpclient.Init(httpclient.NewRequestConfig("fixture", map[string]interface{}{
+		"method":          http.MethodPost,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+		"proxy":           map[string]interface{}{"enabled": true},
+	}))
+
+	// the proxied requests are recorded with their own method rather than the one configured
+	recorder := httptest.NewRecorder()
+	assert.NoError(t, httpclient.Proxy(context.Background(), "fixture", "/echo", recorder,
+		httptest.NewRequest(http.MethodPut, "/moxy/echo", strings.NewReader("a"))))
+	assert.Equal(t, http.MethodPut, recorder.Body.String())
+	assert.NoError(t, fixtures.Close())
+	data, err := ioutil.ReadFile(filepath.Join(directory, "fixture.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"method": "PUT"`)
+}
+
+func TestFixtures(t *testing.T) {
+	u := &upstream{handler: func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		_, _ = writer.Write([]byte(request.URL.Query().Get("q") + " " + string(body)))
+	}}
+	server := httptest.NewServer(u)
+	defer server.Close()
+	directory := t.TempDir()
+
+	// the exchanges are recorded the first time
+	fixtures := initFixturesClient(t, server.URL, httpclient.FixturesConfig{Directory: directory})
+	body, err := requestFixture("a", `{"a": 1, "b": 2}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `a {"a": 1, "b": 2}`, body)
+	assert.NoError(t, fixtures.Close())
+	assert.FileExists(t, filepath.Join(directory, "fixture.json"))
+	assert.Equal(t, int64(1), u.getCalls())
+
+	// and replayed afterwards, with the json bodies matched regardless of their formatting
+	fixtures = initFixturesClient(t, server.URL, httpclient.FixturesConfig{Directory: directory, Strict: true})
+	body, err = requestFixture("a", `{"b":2,"a":1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `a {"a": 1, "b": 2}`, body)
+	_, err = requestFixture("b", `{"a": 1, "b": 2}`)
+	assert.True(t, errors.Is(err, httpclient.ErrUnmatchedFixture))
+	assert.Equal(t, int64(1), u.getCalls())
+	assert.NoError(t, fixtures.Close())
+
+	// the unmatched requests are made when not strict, unless the parts they differ in are not matched
+	initFixturesClient(t, server.URL, httpclient.FixturesConfig{Directory: directory,
+		Match: []string{constants.MethodFixtureMatch, constants.PathFixtureMatch, constants.BodyFixtureMatch}})
+	body, err = requestFixture("b", `{"a": 1, "b": 2}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `a {"a": 1, "b": 2}`, body)
+	body, err = requestFixture("b", `{"a": 2}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `b {"a": 2}`, body)
+	assert.Equal(t, int64(2), u.getCalls())
+}
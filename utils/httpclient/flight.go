@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup coalesces the concurrent fetches of the same response into a single upstream call
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done     chan struct{}
+	response *cachedResponse
+	err      error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: make(map[string]*flight)}
+}
+
+// do is used to call fetch for the key, unless a call for it is in flight already, in which case its result is shared
+// The fetch is detached from the context of the caller starting it, keeping its values and its deadline, so that the
+// caller going away fails neither the fetch nor the others waiting for it. Every caller stops waiting once its own
+// context is done.
+func (g *flightGroup) do(ctx context.Context, key string,
+	fetch func(ctx context.Context) (*cachedResponse, error)) (*cachedResponse, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		g.flights[key] = f
+		go g.run(ctx, key, f, fetch)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.response, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, f *flight,
+	fetch func(ctx context.Context) (*cachedResponse, error)) {
+	var detached context.Context = valuesContext{Context: context.Background(), values: ctx}
+	cancel := func() {}
+	if deadline, ok := ctx.Deadline(); ok {
+		detached, cancel = context.WithDeadline(detached, deadline)
+	}
+	defer cancel()
+	f.response, f.err = fetch(detached)
+
+	g.mu.Lock()
+	delete(g.flights, key)
+	g.mu.Unlock()
+	close(f.done)
+}
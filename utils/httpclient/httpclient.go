@@ -1,33 +1,508 @@
 package httpclient
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	httpclient "github.com/angel-one/go-http-client"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"io"
 	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Client is the set of methods for the http client
 type Client interface {
-	Request(request *httpclient.Request) (*http.Response, error)
+	Request(request *Request) (*http.Response, error)
 }
 
-var client *httpclient.Client
+// RequestConfig is the configuration for a request, along with the caching of its responses and their fallback
+type RequestConfig struct {
+	name     string
+	method   string
+	url      string
+	cache    CacheConfig
+	fallback FallbackConfig
+	attempts AttemptsConfig
+	proxy    ProxyConfig
+	// auth is kept as it is, for the signer registered for its type to make sense of it
+	auth   map[string]interface{}
+	config *httpclient.RequestConfig
+	// configs are kept to tell whether the configuration changed when the client is initialised again
+	configs map[string]interface{}
+}
+
+// Request is a request to be made using one of the configured request configs
+type Request struct {
+	name       string
+	ctx        context.Context
+	method     string
+	url        string
+	path       string
+	pathParams map[string]string
+	query      map[string]string
+	headers    map[string]string
+	body       io.Reader
+	// length is the length of the body when it is known, for the proxied requests
+	length int64
+	// err is an error building the request, returned when it is made
+	err error
+}
+
+type client struct {
+	client    *httpclient.Client
+	configs   map[string]*RequestConfig
+	caches    map[string]*responseCache
+	fallbacks map[string]*fallback
+	handlers  map[string]Handler
+	proxies   map[string]*proxy
+}
+
+var (
+	mu       sync.RWMutex
+	instance = &client{}
+)
+
+// Init is used to initialise the http client with the request configs
+// It can be called again with the changed configs, the requests whose configs did not change keep their caches
+// and their last known good responses.
+func Init(configs ...*RequestConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	c := &client{
+		configs:   make(map[string]*RequestConfig, len(configs)),
+		caches:    make(map[string]*responseCache),
+		fallbacks: make(map[string]*fallback),
+		handlers:  make(map[string]Handler, len(configs)),
+		proxies:   make(map[string]*proxy),
+	}
+	requestConfigs := make([]*httpclient.RequestConfig, 0, len(configs))
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		c.configs[config.name] = config
+		requestConfigs = append(requestConfigs, config.config)
+		previous, ok := instance.configs[config.name]
+		switch {
+		case !ok:
+			log.Info(context.Background()).Str(constants.RequestNameKey, config.name).Msg("http request added")
+		case !reflect.DeepEqual(previous.configs, config.configs):
+			log.Info(context.Background()).Str(constants.RequestNameKey, config.name).Msg("http request changed")
+		default:
+			if cache, ok := instance.caches[config.name]; ok {
+				c.caches[config.name] = cache
+			}
+			if fallback, ok := instance.fallbacks[config.name]; ok {
+				c.fallbacks[config.name] = fallback
+			}
+			if p, ok := instance.proxies[config.name]; ok {
+				// the connections to the upstream are kept, the handler is built again along with the rest
+				c.proxies[config.name] = &proxy{config: p.config, client: p.client}
+			}
+			continue
+		}
+		if config.cache.Enabled {
+			c.caches[config.name] = newResponseCache(config.name, config.cache)
+		}
+		if config.fallback.Strategy != "" {
+			c.fallbacks[config.name] = newFallback(config)
+		}
+		if config.proxy.Enabled {
+			c.proxies[config.name] = newProxy(config)
+		}
+	}
+	for name := range instance.configs {
+		if _, ok := c.configs[name]; !ok {
+			log.Info(context.Background()).Str(constants.RequestNameKey, name).Msg("http request removed")
+		}
+	}
+	for _, fallback := range c.fallbacks {
+		if fallback.alternate != nil {
+			requestConfigs = append(requestConfigs, fallback.alternate.config)
+		}
+	}
+	c.client = httpclient.ConfigureHTTPClient(requestConfigs...)
+	for name, config := range c.configs {
+		c.handlers[name] = chain(config, c.do)
+	}
+	for _, fallback := range c.fallbacks {
+		if fallback.alternate != nil {
+			c.handlers[fallback.alternate.name] = chain(fallback.alternate, c.do)
+		}
+	}
+	for name, p := range c.proxies {
+		// the bodies of the proxied requests are streamed, so they cannot be attempted again
+		config := *c.configs[name]
+		config.attempts = AttemptsConfig{}
+		p.handler = chain(&config, breakCircuit(&config, p.stream))
+	}
+	instance = c
+}
 
-// Init is used to initialise the http client
-func Init(configs ...*httpclient.RequestConfig) {
-	client = httpclient.ConfigureHTTPClient(configs...)
+// NewRequestConfigs is used to create the request configs for all the entries of the configs, keyed by their names
+func NewRequestConfigs(configs map[string]interface{}) []*RequestConfig {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	requestConfigs := make([]*RequestConfig, 0, len(names))
+	for _, name := range names {
+		requestConfigs = append(requestConfigs, NewRequestConfig(name, getMap(configs, name)))
+	}
+	return requestConfigs
 }
 
 // NewRequestConfig is used to create a new request config
-func NewRequestConfig(name string, configs map[string]interface{}) *httpclient.RequestConfig {
-	return httpclient.NewRequestConfig(name, configs)
+// The cache block of the configs enables the caching of the responses for the request,
+// and the fallback block the responses served in their place when the request fails.
+// The attempts block sets the timeouts of the attempts, the retries, the hedges and the budget for them,
+// and the auth block the signer the requests are authenticated with.
+// The proxy block lets the incoming requests be proxied to the upstream using Proxy.
+func NewRequestConfig(name string, configs map[string]interface{}) *RequestConfig {
+	cache := getMap(configs, constants.HTTPCacheConfigKey)
+	fallback := getMap(configs, constants.HTTPFallbackConfigKey)
+	attempts := getMap(configs, constants.HTTPAttemptsConfigKey)
+	return &RequestConfig{
+		name:   name,
+		method: strings.ToUpper(getString(configs, constants.HTTPMethodConfigKey)),
+		url:    getString(configs, constants.HTTPURLConfigKey),
+		cache: CacheConfig{
+			Enabled: getBool(cache, constants.HTTPCacheEnabledConfigKey),
+			Size:    getInt(cache, constants.HTTPCacheSizeConfigKey),
+			TTL:     time.Duration(getInt(cache, constants.HTTPCacheTTLInMillisConfigKey)) * time.Millisecond,
+			StaleWhileRevalidate: time.Duration(getInt(cache,
+				constants.HTTPCacheStaleWhileRevalidateInMillisConfigKey)) * time.Millisecond,
+			StaleIfError: time.Duration(getInt(cache,
+				constants.HTTPCacheStaleIfErrorInMillisConfigKey)) * time.Millisecond,
+		},
+		fallback: FallbackConfig{
+			Strategy:    strings.ToLower(getString(fallback, constants.HTTPFallbackStrategyConfigKey)),
+			Size:        getInt(fallback, constants.HTTPFallbackSizeConfigKey),
+			StatusCode:  getInt(fallback, constants.HTTPFallbackStatusCodeConfigKey),
+			ContentType: getString(fallback, constants.HTTPFallbackContentTypeConfigKey),
+			Body:        getString(fallback, constants.HTTPFallbackBodyConfigKey),
+			URL:         getString(fallback, constants.HTTPFallbackURLConfigKey),
+		},
+		attempts: AttemptsConfig{
+			Timeout:              getMillis(attempts, constants.HTTPAttemptTimeoutInMillisConfigKey),
+			Deadline:             getMillis(attempts, constants.HTTPAttemptsDeadlineInMillisConfigKey),
+			Retries:              getInt(attempts, constants.HTTPRetriesConfigKey),
+			RetryBackoff:         getMillis(attempts, constants.HTTPRetryBackoffInMillisConfigKey),
+			HedgeAfterPercentile: getFloat(attempts, constants.HTTPHedgeAfterPercentileConfigKey),
+			HedgeDelay:           getMillis(attempts, constants.HTTPHedgeDelayInMillisConfigKey),
+			MaxHedges:            getInt(attempts, constants.HTTPMaxHedgesConfigKey),
+			RetryBudgetPercent:   getFloat(attempts, constants.HTTPRetryBudgetPercentConfigKey),
+			MinRetriesPerSecond:  getInt(attempts, constants.HTTPMinRetriesPerSecondConfigKey),
+		},
+		proxy:   getProxyConfig(getMap(configs, constants.HTTPProxyConfigKey)),
+		auth:    getMap(configs, constants.HTTPAuthConfigKey),
+		config:  httpclient.NewRequestConfig(name, configs),
+		configs: configs,
+	}
 }
 
 // NewRequest is used to create a new request
-func NewRequest(name string) *httpclient.Request {
-	return httpclient.NewRequest(name)
+func NewRequest(name string) *Request {
+	return &Request{name: name}
 }
 
 // Get is used to get the client instance
 func Get() Client {
-	return client
+	return getClient()
+}
+
+// SetContext is used to set the context for the request
+func (r *Request) SetContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// SetURL is used to set the url for the request, the configured url is used when not set
+func (r *Request) SetURL(url string) *Request {
+	r.url = url
+	return r
+}
+
+// SetPath is used to set the path for the request, appended to the url
+// The path can hold params like /counters/{key}, replaced by the path params set.
+func (r *Request) SetPath(path string) *Request {
+	r.path = path
+	return r
+}
+
+// SetPathParam is used to set a path param for the request, escaped when replaced in the path
+func (r *Request) SetPathParam(param, value string) *Request {
+	if r.pathParams == nil {
+		r.pathParams = make(map[string]string)
+	}
+	r.pathParams[param] = value
+	return r
+}
+
+// SetPathParams is used to set multiple path params for the request
+func (r *Request) SetPathParams(params map[string]string) *Request {
+	for param, value := range params {
+		r.SetPathParam(param, value)
+	}
+	return r
+}
+
+// SetQueryParam is used to set a query param for the request
+func (r *Request) SetQueryParam(param, value string) *Request {
+	if r.query == nil {
+		r.query = make(map[string]string)
+	}
+	r.query[param] = value
+	return r
+}
+
+// SetQueryParams is used to set multiple query params for the request
+func (r *Request) SetQueryParams(params map[string]string) *Request {
+	for param, value := range params {
+		r.SetQueryParam(param, value)
+	}
+	return r
+}
+
+// SetHeaderParam is used to set a header for the request
+func (r *Request) SetHeaderParam(param, value string) *Request {
+	if r.headers == nil {
+		r.headers = make(map[string]string)
+	}
+	r.headers[http.CanonicalHeaderKey(param)] = value
+	return r
+}
+
+// SetHeaderParams is used to set multiple headers for the request
+func (r *Request) SetHeaderParams(params map[string]string) *Request {
+	for param, value := range params {
+		r.SetHeaderParam(param, value)
+	}
+	return r
+}
+
+// SetBody is used to set the body for the request
+func (r *Request) SetBody(body io.Reader) *Request {
+	r.body = body
+	return r
+}
+
+// SetJSONBody is used to set the value encoded as json as the body for the request
+func (r *Request) SetJSONBody(value interface{}) *Request {
+	body, err := json.Marshal(value)
+	if err != nil {
+		r.err = fmt.Errorf("error encoding the body of the %s request: %w", r.name, err)
+		return r
+	}
+	return r.SetHeaderParam(constants.ContentTypeHeader, constants.JSONContentType).SetBody(bytes.NewReader(body))
+}
+
+// Name is used to get the name of the request config the request is made with
+func (r *Request) Name() string {
+	return r.name
+}
+
+// Method is used to get the method of the request, known once it is being made
+func (r *Request) Method() string {
+	if r.method == "" {
+		return http.MethodGet
+	}
+	return r.method
+}
+
+// URL is used to get the url of the request, including its path once it is being made
+func (r *Request) URL() string {
+	return r.url
+}
+
+// Context is used to get the context of the request
+func (r *Request) Context() context.Context {
+	return r.getContext()
+}
+
+func (r *Request) getContext() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// getURL is used to get the url of the request, built from the configured one when not set
+func (r *Request) getURL(config *RequestConfig) (string, error) {
+	u := r.url
+	if u == "" {
+		u = config.url
+	}
+	if r.path == "" {
+		return u, nil
+	}
+	path := r.path
+	for param, value := range r.pathParams {
+		path = strings.ReplaceAll(path, "{"+param+"}", url.PathEscape(value))
+	}
+	if strings.ContainsAny(path, "{}") {
+		return "", fmt.Errorf("missing path params for path %s of the %s request", path, r.name)
+	}
+	return strings.TrimRight(u, "/") + "/" + strings.TrimLeft(path, "/"), nil
+}
+
+// clone is used to get a copy of the request which can be changed without affecting this one
+func (r *Request) clone(ctx context.Context) *Request {
+	clone := &Request{name: r.name, ctx: ctx, method: r.method, url: r.url, path: r.path, body: r.body,
+		length: r.length, err: r.err}
+	clone.SetPathParams(r.pathParams)
+	clone.SetQueryParams(r.query)
+	clone.SetHeaderParams(r.headers)
+	return clone
+}
+
+func (c *client) Request(request *Request) (*http.Response, error) {
+	if request.err != nil {
+		return nil, request.err
+	}
+	config, ok := c.configs[request.name]
+	if !ok {
+		return nil, fmt.Errorf("%w with name %s", ErrUnknownRequest, request.name)
+	}
+	if _, err := request.getURL(config); err != nil {
+		return nil, err
+	}
+	// the fallback gets the request before its url is built, to build it again for the alternate url
+	if fallback, ok := c.fallbacks[request.name]; ok {
+		var alternate Handler
+		if fallback.alternate != nil {
+			alternate = c.handlers[fallback.alternate.name]
+		}
+		return fallback.get(config, request, c.serve(config), alternate)
+	}
+	return c.serve(config)(request)
+}
+
+// serve is used to get the handler making the request with its url built, serving the cached responses if any
+func (c *client) serve(config *RequestConfig) Handler {
+	return func(request *Request) (*http.Response, error) {
+		u, err := request.getURL(config)
+		if err != nil {
+			return nil, err
+		}
+		request = request.clone(request.ctx)
+		request.url, request.path = u, ""
+		if request.method == "" {
+			// the middlewares get the method from the request, as the proxied requests have one of their own
+			request.method = config.method
+		}
+		// the cached responses are served without going through the middlewares
+		handler := c.handlers[request.name]
+		if cache, ok := c.caches[request.name]; ok && isCacheable(config, request) {
+			return cache.get(config, request, handler)
+		}
+		return handler(request)
+	}
+}
+
+func (c *client) do(request *Request) (*http.Response, error) {
+	r := httpclient.NewRequest(request.name).
+		SetContext(request.getContext()).
+		SetURL(request.url).
+		SetQueryParams(request.query).
+		SetHeaderParams(request.headers)
+	if request.body != nil {
+		r.SetBody(request.body)
+	}
+	return c.client.Request(r)
+}
+
+// the configuration maps come from yaml, so the keys are matched regardless of their case
+func getOption(configs map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := configs[key]; ok {
+		return value, true
+	}
+	for k, value := range configs {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func getString(configs map[string]interface{}, key string) string {
+	value, _ := getOption(configs, key)
+	s, _ := value.(string)
+	return s
+}
+
+func getBool(configs map[string]interface{}, key string) bool {
+	value, _ := getOption(configs, key)
+	b, _ := value.(bool)
+	return b
+}
+
+func getInt(configs map[string]interface{}, key string) int {
+	value, _ := getOption(configs, key)
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+func getFloat(configs map[string]interface{}, key string) float64 {
+	value, _ := getOption(configs, key)
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+func getMillis(configs map[string]interface{}, key string) time.Duration {
+	return time.Duration(getFloat(configs, key) * float64(time.Millisecond))
+}
+
+func getStrings(configs map[string]interface{}, key string) []string {
+	value, _ := getOption(configs, key)
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	strs := make([]string, 0, len(list))
+	for _, s := range list {
+		strs = append(strs, fmt.Sprint(s))
+	}
+	return strs
+}
+
+func getMap(configs map[string]interface{}, key string) map[string]interface{} {
+	value, _ := getOption(configs, key)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = value
+		}
+		return m
+	default:
+		return nil
+	}
 }
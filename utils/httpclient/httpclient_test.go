@@ -0,0 +1,97 @@
+package httpclient_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestConfigs(t *testing.T) {
+	u := &upstream{handler: func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte("ok"))
+	}}
+	server := httptest.NewServer(u)
+	defer server.Close()
+	getConfigs := func(ttl int) map[string]interface{} {
+		return map[string]interface{}{
+			"cached": map[string]interface{}{
+				"method":          http.MethodGet,
+				"url":             server.URL,
+				"timeoutinmillis": 1000,
+				"cache":           map[string]interface{}{"enabled": true, "ttlInMillis": ttl},
+			},
+			"other": map[string]interface{}{"method": http.MethodGet, "url": server.URL, "timeoutinmillis": 1000},
+		}
+	}
+	getStatus := func(name string) string {
+		response, err := httpclient.Get().Request(httpclient.NewRequest(name))
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		_ = response.Body.Close()
+		return response.Header.Get(constants.CacheStatusHeader)
+	}
+
+	httpclient.Init(httpclient.NewRequestConfigs(getConfigs(60000))...)
+	assert.Equal(t, constants.MissCacheStatus, getStatus("cached"))
+	assert.Empty(t, getStatus("other"))
+
+	// the unchanged requests keep their caches, the changed ones start afresh
+	httpclient.Init(httpclient.NewRequestConfigs(getConfigs(60000))...)
+	assert.Equal(t, constants.HitCacheStatus, getStatus("cached"))
+	httpclient.Init(httpclient.NewRequestConfigs(getConfigs(30000))...)
+	assert.Equal(t, constants.MissCacheStatus, getStatus("cached"))
+
+	configs := getConfigs(30000)
+	delete(configs, "other")
+	httpclient.Init(httpclient.NewRequestConfigs(configs)...)
+	_, err := httpclient.Get().Request(httpclient.NewRequest("other"))
+	assert.Error(t, err)
+}
+
+func TestPathParamsAndJSONBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		_ = json.NewEncoder(writer).Encode(map[string]string{
+			"path":        request.URL.EscapedPath(),
+			"query":       request.URL.RawQuery,
+			"contentType": request.Header.Get(constants.ContentTypeHeader),
+			"body":        string(body),
+		})
+	}))
+	defer server.Close()
+	httpclient.Init(httpclient.NewRequestConfig("counters", map[string]interface{}{
+		"method":          http.MethodPost,
+		"url":             server.URL + "/api/",
+		"timeoutinmillis": 1000,
+	}))
+
+	response, err := httpclient.Get().Request(httpclient.NewRequest("counters").
+		SetPath("/counters/{key}/increment").
+		SetPathParam("key", "a b").
+		SetQueryParam("by", "2").
+		SetJSONBody(map[string]int{"count": 1}))
+	assert.NoError(t, err)
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	var echoed map[string]string
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&echoed))
+	assert.Equal(t, map[string]string{
+		"path":        "/api/counters/a%20b/increment",
+		"query":       "by=2",
+		"contentType": constants.JSONContentType,
+		"body":        `{"count":1}`,
+	}, echoed)
+
+	_, err = httpclient.Get().Request(httpclient.NewRequest("counters").SetPath("/counters/{key}"))
+	assert.Error(t, err)
+	_, err = httpclient.Get().Request(httpclient.NewRequest("counters").SetJSONBody(make(chan int)))
+	assert.Error(t, err)
+}
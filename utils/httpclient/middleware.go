@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"fmt"
+	goUtilsConstants "github.com/angel-one/go-utils/constants"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// Handler is used to make the request
+type Handler func(request *Request) (*http.Response, error)
+
+// Middleware is used to wrap the handler making the requests with the request config
+type Middleware func(config *RequestConfig, next Handler) Handler
+
+// middlewares are applied in order, the first one seeing the request first
+var middlewares = []Middleware{propagate, instrument, attempt, authenticate, guard}
+
+// traceHeaders are the headers of the incoming request carried over to the upstream requests
+var traceHeaders = []string{
+	constants.TraceParentHeader,
+	constants.TraceStateHeader,
+	constants.B3Header,
+	constants.B3TraceIDHeader,
+	constants.B3SpanIDHeader,
+	constants.B3ParentSpanIDHeader,
+	constants.B3SampledHeader,
+	constants.B3FlagsHeader,
+}
+
+// Use is used to add the middlewares to the ones the requests go through
+// They apply to the requests configured by the later calls to Init.
+func Use(m ...Middleware) {
+	mu.Lock()
+	defer mu.Unlock()
+	middlewares = append(middlewares, m...)
+}
+
+// GetTraceHeaders is used to get the trace headers out of the headers of an incoming request
+// They are expected on the context under the trace headers key, to be propagated to the upstream requests.
+func GetTraceHeaders(header http.Header) http.Header {
+	trace := make(http.Header)
+	for _, name := range traceHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			trace[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return trace
+}
+
+func chain(config *RequestConfig, handler Handler) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](config, handler)
+	}
+	return handler
+}
+
+// propagate carries the request id and the trace headers of the incoming request on the context over to the request
+func propagate(_ *RequestConfig, next Handler) Handler {
+	return func(request *Request) (*http.Response, error) {
+		ctx := request.getContext()
+		if id, ok := ctx.Value(goUtilsConstants.IDLogParam).(string); ok && id != "" {
+			if _, ok = request.headers[http.CanonicalHeaderKey(goUtilsConstants.RequestIDHeader)]; !ok {
+				request.SetHeaderParam(goUtilsConstants.RequestIDHeader, id)
+			}
+		}
+		if trace, ok := ctx.Value(constants.TraceHeadersKey).(http.Header); ok {
+			for name := range trace {
+				if _, ok = request.headers[name]; !ok {
+					request.SetHeaderParam(name, trace.Get(name))
+				}
+			}
+		}
+		return next(request)
+	}
+}
+
+// instrument logs every request with the number of attempts made for it and records its latency, attempts and failures
+func instrument(config *RequestConfig, next Handler) Handler {
+	latency := metrics.GetHistogram(fmt.Sprintf(constants.HTTPLatencyMetric, config.name))
+	failures := metrics.GetCounter(fmt.Sprintf(constants.HTTPErrorsMetric, config.name))
+	totalAttempts := metrics.GetCounter(fmt.Sprintf(constants.HTTPAttemptsMetric, config.name))
+	return func(request *Request) (*http.Response, error) {
+		// the proxied requests have a method of their own
+		method := request.method
+		if method == "" {
+			method = config.method
+		}
+		if method == "" {
+			method = http.MethodGet
+		}
+		ctx := request.getContext()
+		// a connection is got for every attempt, including the retries made by the underlying client
+		attempts := int32(0)
+		request.ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			GetConn: func(string) {
+				atomic.AddInt32(&attempts, 1)
+			},
+		})
+
+		start := time.Now()
+		response, err := next(request)
+		elapsed := time.Since(start)
+		latency.ObserveDuration(elapsed)
+		totalAttempts.Add(int64(atomic.LoadInt32(&attempts)))
+
+		status := 0
+		if response != nil {
+			status = response.StatusCode
+		}
+		event := log.Info(ctx)
+		if err != nil || status >= http.StatusInternalServerError {
+			failures.Inc()
+			event = log.Warn(ctx).Err(err)
+		}
+		event.Str(constants.RequestNameKey, config.name).
+			Str(constants.MethodKey, method).
+			Str(constants.URLKey, request.url).
+			Int(constants.StatusCodeKey, status).
+			Dur(constants.LatencyKey, elapsed).
+			Int32(constants.AttemptsKey, atomic.LoadInt32(&attempts)).
+			Msg("http request")
+		return response, err
+	}
+}
@@ -0,0 +1,77 @@
+package httpclient_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPropagation(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+		received = request.Header
+	}))
+	defer server.Close()
+	httpclient.Init(httpclient.NewRequestConfig("propagated", map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+	}))
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set("id", "request-1")
+	ctx.Set(constants.TraceHeadersKey, httpclient.GetTraceHeaders(http.Header{
+		"Traceparent":   []string{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		"Authorization": []string{"secret"},
+	}))
+	response, err := httpclient.Get().Request(httpclient.NewRequest("propagated").SetContext(ctx))
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, "request-1", received.Get("X-requestId"))
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", received.Get("traceparent"))
+	assert.Empty(t, received.Get("Authorization"))
+}
+
+func TestInstrumentation(t *testing.T) {
+	calls := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	httpclient.Init(httpclient.NewRequestConfig("instrumented", map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		"retrycount":      2,
+		"backoffpolicy": map[string]interface{}{
+			"constantbackoff": map[string]interface{}{"intervalinmillis": 1, "maxjitterintervalinmillis": 1},
+		},
+	}))
+	before := metrics.GetSnapshot()
+
+	response, err := httpclient.Get().Request(httpclient.NewRequest("instrumented"))
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	after := metrics.GetSnapshot()
+	// the retry made by the underlying client is counted as an attempt
+	assert.Equal(t, int64(2), after.Counters["http.instrumented.attempts"]-before.Counters["http.instrumented.attempts"])
+	assert.Equal(t, int64(1), after.Histograms["http.instrumented.latency"].Count-
+		before.Histograms["http.instrumented.latency"].Count)
+	assert.Equal(t, before.Counters["http.instrumented.errors"], after.Counters["http.instrumented.errors"])
+
+	server.Close()
+	_, err = httpclient.Get().Request(httpclient.NewRequest("instrumented"))
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), metrics.GetSnapshot().Counters["http.instrumented.errors"]-
+		after.Counters["http.instrumented.errors"])
+}
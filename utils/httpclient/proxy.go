@@ -0,0 +1,407 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ProxyConfig is the configuration for proxying the incoming requests to an upstream
+type ProxyConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxRequestBodySize and MaxResponseBodySize are the largest bodies in bytes forwarded either way
+	MaxRequestBodySize  int64 `json:"maxRequestBodySize,omitempty"`
+	MaxResponseBodySize int64 `json:"maxResponseBodySize,omitempty"`
+	// Headers are the headers of the incoming requests forwarded to the upstream, the proxy headers by default
+	Headers []string `json:"headers,omitempty"`
+}
+
+var (
+	// ErrProxyDisabled is returned for proxying to an upstream which does not have the proxy enabled
+	ErrProxyDisabled = errors.New("proxy not enabled")
+	// ErrRequestBodyTooLarge is returned for the incoming requests with a body larger than the upstream allows
+	ErrRequestBodyTooLarge = errors.New("request body too large")
+	// ErrResponseBodyTooLarge is returned for the upstream responses with a body larger than the upstream allows
+	ErrResponseBodyTooLarge = errors.New("response body too large")
+	// ErrInvalidProxyPath is returned for the paths which would leave the path of the upstream url
+	ErrInvalidProxyPath = errors.New("invalid proxy path")
+)
+
+// proxyHeaders are the headers of the incoming requests forwarded to the upstreams, when they configure none
+var proxyHeaders = []string{
+	constants.AcceptHeader,
+	constants.AcceptEncodingHeader,
+	constants.AcceptLanguageHeader,
+	constants.ContentTypeHeader,
+	constants.ContentEncodingHeader,
+	constants.IfNoneMatchHeader,
+	constants.IfModifiedSinceHeader,
+	constants.UserAgentHeader,
+}
+
+// hopByHopHeaders are the headers meant for a single connection, never forwarded either way,
+// along with the ones listed in the connection header
+var hopByHopHeaders = []string{
+	constants.ConnectionHeader,
+	constants.KeepAliveHeader,
+	constants.ProxyAuthenticateHeader,
+	constants.ProxyAuthorizationHeader,
+	constants.ProxyConnectionHeader,
+	constants.TEHeader,
+	constants.TrailerHeader,
+	constants.TransferEncodingHeader,
+	constants.UpgradeHeader,
+}
+
+// proxy is what the incoming requests are proxied to an upstream with
+type proxy struct {
+	config  ProxyConfig
+	client  *http.Client
+	handler Handler
+}
+
+// valuesContext is a context carrying the values of another one, without being cancelled along with it
+// The proxied requests get the request id and the trace headers of the context they are proxied with, while being
+// cancelled when the client goes away, and the coalesced fetches keep the values of the caller starting them.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+// limitedBody fails the reads going over the max size, remembering it did
+type limitedBody struct {
+	body     io.ReadCloser
+	left     int64
+	exceeded bool
+}
+
+// Proxy is used to forward the incoming request to the upstream with the name, at the path under its url,
+// streaming the response back with its status and headers
+// The method, the query, the headers allowed and the body of the request are forwarded, the hop-by-hop headers
+// never are. The request goes through the middlewares and the circuit of the upstream, but is neither retried
+// nor hedged, its body being streamed, and is not served from the cache or the fallback. Nothing is written when
+// an error is returned, unless the response fails on the way, in which case the connection is closed.
+func Proxy(ctx context.Context, name, path string, writer http.ResponseWriter, request *http.Request) error {
+	c := getClient()
+	config, ok := c.configs[name]
+	if !ok {
+		return fmt.Errorf("%w with name %s", ErrUnknownRequest, name)
+	}
+	p, ok := c.proxies[name]
+	if !ok {
+		return fmt.Errorf("%w for %s", ErrProxyDisabled, name)
+	}
+	if request.ContentLength > p.config.MaxRequestBodySize {
+		return fmt.Errorf("%w, the %s requests allow %d bytes", ErrRequestBodyTooLarge, name,
+			p.config.MaxRequestBodySize)
+	}
+	u, err := url.Parse(config.url)
+	if err != nil {
+		return err
+	}
+	u.Path, err = joinProxyPath(u.Path, path)
+	if err != nil {
+		return err
+	}
+	u.RawPath = ""
+	if request.URL.RawQuery != "" {
+		u.RawQuery = strings.TrimLeft(u.RawQuery+"&"+request.URL.RawQuery, "&")
+	}
+
+	r := NewRequest(name).SetContext(valuesContext{Context: request.Context(), values: ctx})
+	r.method, r.url, r.length = request.Method, u.String(), request.ContentLength
+	connectionHeaders := getConnectionHeaders(request.Header)
+	for _, header := range p.config.Headers {
+		if value := request.Header.Get(header); value != "" && !connectionHeaders[http.CanonicalHeaderKey(header)] {
+			r.SetHeaderParam(header, value)
+		}
+	}
+	setForwardedHeaders(r, request)
+	var body *limitedBody
+	if request.Body != nil && request.Body != http.NoBody {
+		body = &limitedBody{body: request.Body, left: p.config.MaxRequestBodySize}
+		r.body = body
+	}
+
+	response, err := p.handler(r)
+	if body != nil && body.exceeded {
+		if err == nil {
+			_ = response.Body.Close()
+		}
+		return fmt.Errorf("%w, the %s requests allow %d bytes", ErrRequestBodyTooLarge, name,
+			p.config.MaxRequestBodySize)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.ContentLength > p.config.MaxResponseBodySize {
+		return fmt.Errorf("%w, the %s responses allow %d bytes", ErrResponseBodyTooLarge, name,
+			p.config.MaxResponseBodySize)
+	}
+
+	connectionHeaders = getConnectionHeaders(response.Header)
+	for header, values := range response.Header {
+		if !connectionHeaders[header] {
+			writer.Header()[header] = values
+		}
+	}
+	writer.WriteHeader(response.StatusCode)
+	if err = copyBody(writer, response.Body, p.config.MaxResponseBodySize); err != nil {
+		// the status is already sent, the client is let know the response is incomplete by closing the connection
+		log.Warn(ctx).Err(err).Str(constants.RequestNameKey, name).Msg("error streaming the proxied response")
+		abort(writer)
+		if errors.Is(err, ErrResponseBodyTooLarge) {
+			return fmt.Errorf("%w, the %s responses allow %d bytes", err, name, p.config.MaxResponseBodySize)
+		}
+		return err
+	}
+	return nil
+}
+
+// joinProxyPath is used to join the path to the base path of the upstream url, cleaning it of the dot segments
+// The paths leaving the base path are rejected, including the ones with the dots escaped, which the upstream could
+// unescape before resolving them.
+func joinProxyPath(base, p string) (string, error) {
+	base = strings.TrimRight(base, "/")
+	unescaped, err := url.PathUnescape(p)
+	if err != nil || !isUnder(base, path.Clean(base+"/"+unescaped)) {
+		return "", fmt.Errorf("%w %s", ErrInvalidProxyPath, p)
+	}
+	joined := path.Clean(base + "/" + p)
+	if !isUnder(base, joined) {
+		return "", fmt.Errorf("%w %s", ErrInvalidProxyPath, p)
+	}
+	if strings.HasSuffix(p, "/") && joined != "/" {
+		// cleaning drops the trailing slash, which the upstream can tell apart
+		joined += "/"
+	}
+	return joined, nil
+}
+
+func isUnder(base, p string) bool {
+	return p == base || strings.HasPrefix(p, base+"/")
+}
+
+// newProxy is used to create the proxy for the request config, along with the client it streams the requests with
+// The client times the upstream out when it does not respond in time, but not while the response is streamed.
+func newProxy(config *RequestConfig) *proxy {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = getMillis(config.configs, constants.HTTPTimeoutInMillisConfigKey)
+	// the bodies are streamed as they are, encoded or not
+	transport.DisableCompression = true
+	return &proxy{
+		config: config.proxy,
+		client: &http.Client{
+			Transport: transport,
+			// the redirects are for the client to follow
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// stream is used to make the request with its own method and body, returning the response as it comes
+func (p *proxy) stream(request *Request) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(request.getContext(), request.Method(), request.url, request.body)
+	if err != nil {
+		return nil, err
+	}
+	if r.ContentLength == 0 && request.length > 0 {
+		r.ContentLength = request.length
+	}
+	if len(request.query) > 0 {
+		query := r.URL.Query()
+		for param, value := range request.query {
+			query.Set(param, value)
+		}
+		r.URL.RawQuery = query.Encode()
+	}
+	for header, value := range request.headers {
+		r.Header.Set(header, value)
+	}
+	return p.client.Do(r)
+}
+
+// breakCircuit makes the requests through the circuit of the upstream, the one its other requests go through
+// A server error counts as a failure for the circuit, while still being the response proxied.
+func breakCircuit(config *RequestConfig, next Handler) Handler {
+	if !hasCircuit(config) {
+		return next
+	}
+	return func(request *Request) (*http.Response, error) {
+		ctx, cancel := context.WithCancel(request.getContext())
+		request.ctx = ctx
+		results := make(chan result, 1)
+		var mu sync.Mutex
+		started, done := false, false
+		err := hystrix.DoC(ctx, config.name, func(context.Context) error {
+			mu.Lock()
+			if done {
+				mu.Unlock()
+				return nil
+			}
+			started = true
+			mu.Unlock()
+			response, err := next(request)
+			results <- result{response: response, err: err}
+			if err == nil && response.StatusCode >= http.StatusInternalServerError {
+				return fmt.Errorf("upstream responded with status %d", response.StatusCode)
+			}
+			return err
+		}, nil)
+		mu.Lock()
+		done = true
+		mu.Unlock()
+		if !started {
+			cancel()
+			return nil, err
+		}
+		var circuitErr hystrix.CircuitError
+		if errors.As(err, &circuitErr) {
+			// the circuit timed the request out, it is not waited for any more than it takes to cancel it
+			cancel()
+			r := <-results
+			r.close()
+			return nil, err
+		}
+		r := <-results
+		if r.err != nil {
+			cancel()
+			return nil, r.err
+		}
+		return withCancel(r.response, cancel), nil
+	}
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	if value := c.values.Value(key); value != nil {
+		return value
+	}
+	return c.Context.Value(key)
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		b.exceeded = true
+		return 0, ErrRequestBodyTooLarge
+	}
+	// one byte more than what is left is read, to tell whether there is more
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+	n, err := b.body.Read(p)
+	b.left -= int64(n)
+	if b.left < 0 {
+		b.exceeded = true
+		return 0, ErrRequestBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// getConnectionHeaders is used to get the hop-by-hop headers, the ones listed in the connection header included
+func getConnectionHeaders(header http.Header) map[string]bool {
+	headers := make(map[string]bool, len(hopByHopHeaders))
+	for _, name := range hopByHopHeaders {
+		headers[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, value := range header.Values(constants.ConnectionHeader) {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				headers[http.CanonicalHeaderKey(name)] = true
+			}
+		}
+	}
+	return headers
+}
+
+// setForwardedHeaders lets the upstream know who the request came from, and the host and the scheme it came to
+func setForwardedHeaders(r *Request, request *http.Request) {
+	if ip, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		if prior := request.Header.Get(constants.ForwardedForHeader); prior != "" {
+			ip = prior + ", " + ip
+		}
+		r.SetHeaderParam(constants.ForwardedForHeader, ip)
+	}
+	r.SetHeaderParam(constants.ForwardedHostHeader, request.Host)
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	r.SetHeaderParam(constants.ForwardedProtoHeader, scheme)
+}
+
+// copyBody streams the body to the writer, flushing it as it is read
+func copyBody(writer http.ResponseWriter, body io.Reader, max int64) error {
+	flusher, _ := writer.(http.Flusher)
+	buffer := make([]byte, constants.ProxyBufferSize)
+	written := int64(0)
+	for {
+		n, err := body.Read(buffer)
+		if n > 0 {
+			if written += int64(n); written > max {
+				return ErrResponseBodyTooLarge
+			}
+			if _, err := writer.Write(buffer[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// abort closes the connection of the response, for the client to not take the part of it written as complete
+func abort(writer http.ResponseWriter) {
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		return
+	}
+	if conn, _, err := hijacker.Hijack(); err == nil {
+		_ = conn.Close()
+	}
+}
+
+// getProxyConfig is used to get the proxy config of the request, with the defaults for what is not set
+func getProxyConfig(configs map[string]interface{}) ProxyConfig {
+	config := ProxyConfig{
+		Enabled:             getBool(configs, constants.HTTPProxyEnabledConfigKey),
+		MaxRequestBodySize:  int64(getInt(configs, constants.HTTPProxyMaxRequestBodySizeConfigKey)),
+		MaxResponseBodySize: int64(getInt(configs, constants.HTTPProxyMaxResponseBodySizeConfigKey)),
+		Headers:             getStrings(configs, constants.HTTPProxyHeadersConfigKey),
+	}
+	if config.MaxRequestBodySize <= 0 {
+		config.MaxRequestBodySize = constants.DefaultMaxProxyRequestBodySize
+	}
+	if config.MaxResponseBodySize <= 0 {
+		config.MaxResponseBodySize = constants.DefaultMaxProxyResponseBodySize
+	}
+	if len(config.Headers) == 0 {
+		config.Headers = proxyHeaders
+	}
+	return config
+}
@@ -0,0 +1,185 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func initProxyClient(t *testing.T, handler http.HandlerFunc, proxy map[string]interface{}) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	httpclient.Init(httpclient.NewRequestConfig("proxy", map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             server.URL + "/base",
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+		"proxy":           proxy,
+	}))
+}
+
+func proxy(path string, request *http.Request) (*httptest.ResponseRecorder, error) {
+	recorder := httptest.NewRecorder()
+	err := httpclient.Proxy(context.Background(), "proxy", path, recorder, request)
+	return recorder, err
+}
+
+func TestProxy(t *testing.T) {
+	initProxyClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		writer.Header().Set("Content-Type", "text/plain")
+		writer.Header().Set("Connection", "X-Hop")
+		writer.Header().Set("X-Hop", "hop")
+		writer.Header().Set("Keep-Alive", "timeout=5")
+		writer.Header().Set("X-Upstream", "moxy")
+		writer.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(writer, "%s %s?%s %s|%s|%s|%s|%s", request.Method, request.URL.Path, request.URL.RawQuery,
+			body, request.Header.Get("Content-Type"), request.Header.Get("Authorization"),
+			request.Header.Get("Accept-Language"), request.Header.Get("X-Forwarded-For"))
+	}, map[string]interface{}{"enabled": true})
+
+	request := httptest.NewRequest(http.MethodPut, "/moxy/counters/a?x=1&x=2", strings.NewReader(`{"a":1}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer client")
+	request.Header.Set("Connection", "Accept-Language")
+	request.Header.Set("Accept-Language", "en")
+	recorder, err := proxy("/counters/a", request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, `PUT /base/counters/a?x=1&x=2 {"a":1}|application/json|||192.0.2.1`, recorder.Body.String())
+	assert.Equal(t, "moxy", recorder.Header().Get("X-Upstream"))
+	assert.Empty(t, recorder.Header().Get("Connection"))
+	assert.Empty(t, recorder.Header().Get("X-Hop"))
+	assert.Empty(t, recorder.Header().Get("Keep-Alive"))
+}
+
+func TestProxyPath(t *testing.T) {
+	initProxyClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(request.URL.Path))
+	}, map[string]interface{}{"enabled": true})
+
+	for path, expected := range map[string]string{
+		"":                 "/base",
+		"/":                "/base/",
+		"/counters/./a/":   "/base/counters/a/",
+		"/counters/../a":   "/base/a",
+		"/counters/%2e%2e": "/base/counters/%2e%2e",
+	} {
+		recorder, err := proxy(path, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.NoError(t, err, path)
+		assert.Equal(t, expected, recorder.Body.String(), path)
+	}
+
+	// the paths leaving the base path are rejected before the request is made
+	for _, path := range []string{"/..", "/../x", "/a/../../x", "/%2e%2e/x", "/%2E%2E%2Fx", "/a/%2e%2e/%2e%2e"} {
+		_, err := proxy(path, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.True(t, errors.Is(err, httpclient.ErrInvalidProxyPath), path)
+	}
+}
+
+func TestProxyLimits(t *testing.T) {
+	requests := 0
+	initProxyClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		requests++
+		_, _ = ioutil.ReadAll(request.Body)
+		_, _ = writer.Write([]byte(strings.Repeat("a", 10)))
+	}, map[string]interface{}{"enabled": true, "maxRequestBodySize": 4, "maxResponseBodySize": 8})
+
+	// the requests with a known length are rejected before they are made
+	recorder, err := proxy("/", httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	assert.True(t, errors.Is(err, httpclient.ErrRequestBodyTooLarge))
+	assert.False(t, recorder.Flushed)
+	assert.Equal(t, 0, requests)
+
+	// and the ones streamed fail once they go over
+	request := httptest.NewRequest(http.MethodPost, "/", ioutil.NopCloser(strings.NewReader("12345")))
+	request.ContentLength = -1
+	_, err = proxy("/", request)
+	assert.True(t, errors.Is(err, httpclient.ErrRequestBodyTooLarge))
+
+	// the responses with a known length are rejected before anything is written
+	recorder, err = proxy("/", httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, errors.Is(err, httpclient.ErrResponseBodyTooLarge))
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestProxyStreams(t *testing.T) {
+	release := make(chan struct{})
+	initProxyClient(t, func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("first"))
+		writer.(http.Flusher).Flush()
+		<-release
+		_, _ = writer.Write([]byte("second"))
+	}, map[string]interface{}{"enabled": true})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.NoError(t, httpclient.Proxy(request.Context(), "proxy", request.URL.Path, writer, request))
+	}))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/stream")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	// the first part is got while the upstream is yet to send the rest
+	first := make([]byte, 5)
+	_, err = response.Body.Read(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", string(first))
+	close(release)
+	rest, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", string(rest))
+}
+
+func TestProxyDisabled(t *testing.T) {
+	initProxyClient(t, func(writer http.ResponseWriter, request *http.Request) {}, nil)
+	_, err := proxy("/", httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, errors.Is(err, httpclient.ErrProxyDisabled))
+	err = httpclient.Proxy(context.Background(), "unknown", "/", httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, errors.Is(err, httpclient.ErrUnknownRequest))
+}
+
+func TestProxyCircuit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = writer.Write([]byte("down"))
+	}))
+	defer server.Close()
+	httpclient.Init(httpclient.NewRequestConfig("proxy", map[string]interface{}{
+		"method":          http.MethodGet,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+		"hystrixconfig": map[string]interface{}{
+			"hystrixtimeoutinmillis": 1000,
+			"maxconcurrentrequests":  10,
+			"errorpercentthresold":   50,
+			"sleepwindowinmillis":    10000,
+		},
+		"proxy": map[string]interface{}{"enabled": true},
+	}))
+
+	// the server errors are proxied as they are, while counting as failures for the circuit
+	for i := 0; i < 30; i++ {
+		recorder, err := proxy("/", httptest.NewRequest(http.MethodGet, "/", nil))
+		if err != nil {
+			assert.Contains(t, err.Error(), "circuit open")
+			return
+		}
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, "down", recorder.Body.String())
+	}
+	t.Error("circuit did not open")
+}
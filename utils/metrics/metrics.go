@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultLatencyBucketsInMillis are the upper bounds used for latency histograms
+var defaultLatencyBucketsInMillis = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Counter is a monotonically increasing metric
+type Counter struct {
+	value int64
+}
+
+// Inc is used to increment the counter by one
+func (c *Counter) Inc() {
+	atomic.AddInt64(&c.value, 1)
+}
+
+// Add is used to increment the counter by the provided delta
+func (c *Counter) Add(delta int64) {
+	atomic.AddInt64(&c.value, delta)
+}
+
+// Value is used to get the current value of the counter
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.value)
+}
+
+// Histogram is used to track the distribution of the observed values across fixed buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []int64
+	count   int64
+	sum     float64
+}
+
+// Observe is used to record a value in the histogram
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += value
+	for i, bucket := range h.buckets {
+		if value <= bucket {
+			h.counts[i]++
+			return
+		}
+	}
+	h.counts[len(h.buckets)]++
+}
+
+// ObserveDuration is used to record a duration in the histogram in milliseconds
+func (h *Histogram) ObserveDuration(duration time.Duration) {
+	h.Observe(float64(duration) / float64(time.Millisecond))
+}
+
+// HistogramSnapshot is the point in time view of a histogram
+type HistogramSnapshot struct {
+	Count   int64            `json:"count"`
+	Sum     float64          `json:"sum"`
+	Buckets map[string]int64 `json:"buckets"`
+}
+
+func (h *Histogram) snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buckets := make(map[string]int64, len(h.counts))
+	var cumulative int64
+	for i, bucket := range h.buckets {
+		cumulative += h.counts[i]
+		buckets[formatBucket(bucket)] = cumulative
+	}
+	buckets["+Inf"] = h.count
+	return HistogramSnapshot{
+		Count:   h.count,
+		Sum:     h.sum,
+		Buckets: buckets,
+	}
+}
+
+// Snapshot is the point in time view of all the registered metrics
+type Snapshot struct {
+	Counters   map[string]int64             `json:"counters"`
+	Histograms map[string]HistogramSnapshot `json:"histograms"`
+	Gauges     map[string]interface{}       `json:"gauges"`
+}
+
+var (
+	mu         sync.RWMutex
+	counters   = make(map[string]*Counter)
+	histograms = make(map[string]*Histogram)
+	gauges     = make(map[string]func() interface{})
+)
+
+// GetCounter is used to get the counter registered against this name, creating it if required
+func GetCounter(name string) *Counter {
+	mu.RLock()
+	c, ok := counters[name]
+	mu.RUnlock()
+	if ok {
+		return c
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok = counters[name]; !ok {
+		c = &Counter{}
+		counters[name] = c
+	}
+	return c
+}
+
+// GetHistogram is used to get the latency histogram registered against this name, creating it if required
+func GetHistogram(name string) *Histogram {
+	mu.RLock()
+	h, ok := histograms[name]
+	mu.RUnlock()
+	if ok {
+		return h
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if h, ok = histograms[name]; !ok {
+		h = &Histogram{
+			buckets: defaultLatencyBucketsInMillis,
+			counts:  make([]int64, len(defaultLatencyBucketsInMillis)+1),
+		}
+		histograms[name] = h
+	}
+	return h
+}
+
+// RegisterGauge is used to register a function reporting the current value of a gauge against this name
+func RegisterGauge(name string, gauge func() interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	gauges[name] = gauge
+}
+
+// UnregisterGauge is used to remove the gauge registered against this name
+func UnregisterGauge(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(gauges, name)
+}
+
+// GetSnapshot is used to get the current values of all the registered metrics
+func GetSnapshot() Snapshot {
+	mu.RLock()
+	snapshot := Snapshot{
+		Counters:   make(map[string]int64, len(counters)),
+		Histograms: make(map[string]HistogramSnapshot, len(histograms)),
+		Gauges:     make(map[string]interface{}, len(gauges)),
+	}
+	for name, c := range counters {
+		snapshot.Counters[name] = c.Value()
+	}
+	for name, h := range histograms {
+		snapshot.Histograms[name] = h.snapshot()
+	}
+	gaugeFunctions := make(map[string]func() interface{}, len(gauges))
+	for name, g := range gauges {
+		gaugeFunctions[name] = g
+	}
+	mu.RUnlock()
+
+	// gauges are evaluated outside the lock as they may call into other packages
+	for name, g := range gaugeFunctions {
+		snapshot.Gauges[name] = g()
+	}
+	return snapshot
+}
+
+func formatBucket(bucket float64) string {
+	return strconv.FormatFloat(bucket, 'f', -1, 64)
+}
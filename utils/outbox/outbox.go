@@ -0,0 +1,199 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"strings"
+	"time"
+)
+
+// Config is the configuration for the outbox and its relay
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Sink is one of http or file, the http sink posts to the outbox request of the http client
+	Sink string `json:"sink"`
+	// FilePath is where the file sink appends the events, the standard output when empty
+	FilePath     string        `json:"filePath"`
+	PollInterval time.Duration `json:"pollInterval"`
+	BatchSize    int           `json:"batchSize"`
+	// Backoff is the delay before retrying a failed event, doubling after every attempt up to MaxBackoff
+	Backoff    time.Duration `json:"backoff"`
+	MaxBackoff time.Duration `json:"maxBackoff"`
+}
+
+// Event is a change recorded in the outbox to be published
+type Event struct {
+	ID            int64           `json:"id"`
+	Key           string          `json:"key"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	PublishedAt   *time.Time      `json:"publishedAt,omitempty"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	LastError     string          `json:"lastError,omitempty"`
+}
+
+// Filter is used to select the events to list
+type Filter struct {
+	// Status is one of pending or published, all the events are listed when empty
+	Status string
+	Key    string
+	Limit  int
+}
+
+var config Config
+
+// InitOutbox is used to initialize the outbox and start relaying the pending events
+func InitOutbox(ctx context.Context, c Config) error {
+	log.Info(ctx).Interface(constants.OutboxConfigKey, c).Msg("initializing outbox")
+	if !c.Enabled {
+		config = c
+		return nil
+	}
+	s, err := newSink(c)
+	if err != nil {
+		return err
+	}
+	config = c
+	relay = startRelay(s, c)
+	return nil
+}
+
+// Close is used to stop the relay
+func Close() error {
+	if relay == nil {
+		return nil
+	}
+	err := relay.close()
+	relay = nil
+	return err
+}
+
+// Write is used to record the event in the outbox as part of the transaction making the change
+func Write(ctx context.Context, tx *sql.Tx, key, eventType string, payload interface{}) error {
+	if !config.Enabled {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	now := toMillis(time.Now())
+	_, err = tx.ExecContext(database.WithQueryName(ctx, "writeOutboxEvent"),
+		database.Rebind("insert into outbox (event_key, event_type, payload, created_at, attempts, next_attempt_at, "+
+			"last_error) values (?, ?, ?, ?, ?, ?, ?)"), key, eventType, string(data), now, 0, now, "")
+	return err
+}
+
+// List is used to get the events in the outbox, the latest first
+func List(ctx context.Context, filter Filter) ([]Event, error) {
+	conditions := make([]string, 0, 2)
+	args := make([]interface{}, 0, 3)
+	switch filter.Status {
+	case "":
+	case constants.PendingOutboxStatus:
+		conditions = append(conditions, "published_at is null")
+	case constants.PublishedOutboxStatus:
+		conditions = append(conditions, "published_at is not null")
+	default:
+		return nil, fmt.Errorf("invalid outbox status %s provided", filter.Status)
+	}
+	if filter.Key != "" {
+		conditions = append(conditions, "event_key = ?")
+		args = append(args, filter.Key)
+	}
+	query := "select id, event_key, event_type, payload, created_at, published_at, attempts, next_attempt_at, " +
+		"last_error from outbox"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	query += " order by id desc limit ?"
+	args = append(args, getLimit(filter.Limit))
+
+	var events []Event
+	err := database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		var err error
+		events, err = queryEvents(database.WithQueryName(ctx, "listOutboxEvents"), tx, database.Rebind(query),
+			args...)
+		return err
+	})
+	return events, err
+}
+
+// Replay is used to publish the events again, either the one with the id or all the ones for the key
+// It returns the number of events scheduled for publishing.
+func Replay(ctx context.Context, id int64, key string) (int64, error) {
+	query, arg := "update outbox set published_at = null, attempts = ?, next_attempt_at = ?, last_error = ? "+
+		"where id = ?", interface{}(id)
+	if id == 0 {
+		if key == "" {
+			return 0, fmt.Errorf("either the id or the key of the events to replay is required")
+		}
+		query, arg = "update outbox set published_at = null, attempts = ?, next_attempt_at = ?, last_error = ? "+
+			"where event_key = ?", key
+	}
+	var replayed int64
+	err := database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(database.WithQueryName(ctx, "replayOutboxEvents"), database.Rebind(query),
+			0, toMillis(time.Now()), "", arg)
+		if err != nil {
+			return err
+		}
+		replayed, err = result.RowsAffected()
+		return err
+	})
+	return replayed, err
+}
+
+func getLimit(limit int) int {
+	if limit <= 0 || limit > constants.MaxOutboxListLimit {
+		return constants.MaxOutboxListLimit
+	}
+	return limit
+}
+
+func queryEvents(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]Event, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	events := make([]Event, 0)
+	for rows.Next() {
+		var event Event
+		var payload string
+		var createdAt, nextAttemptAt int64
+		var publishedAt sql.NullInt64
+		err = rows.Scan(&event.ID, &event.Key, &event.Type, &payload, &createdAt, &publishedAt, &event.Attempts,
+			&nextAttemptAt, &event.LastError)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = json.RawMessage(payload)
+		event.CreatedAt = fromMillis(createdAt)
+		event.NextAttemptAt = fromMillis(nextAttemptAt)
+		if publishedAt.Valid {
+			published := fromMillis(publishedAt.Int64)
+			event.PublishedAt = &published
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// the times are kept as epoch milliseconds so that the table looks the same for every dialect
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillis(millis int64) time.Time {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC()
+}
@@ -0,0 +1,308 @@
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/database/databasetest"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/sinhashubham95/go-example-project/utils/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+// outboxTable is an in-memory stand in for the outbox table which understands the statements made by the outbox
+type outboxTable struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+}
+
+// the columns of the rows in the order they are selected
+const (
+	idColumn = iota
+	keyColumn
+	typeColumn
+	payloadColumn
+	createdAtColumn
+	publishedAtColumn
+	attemptsColumn
+	nextAttemptAtColumn
+	lastErrorColumn
+)
+
+func (d *outboxTable) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rows = nil
+}
+
+func (d *outboxTable) exec(_, query string, args []driver.Value) (driver.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "insert into outbox"):
+		row := append([]driver.Value{int64(len(d.rows) + 1)}, args[:4]...)
+		row = append(row, nil)
+		d.rows = append(d.rows, append(row, args[4:]...))
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "update outbox set published_at = ?, attempts = ? where id = ?"):
+		return d.update(func(row []driver.Value) bool {
+			return row[idColumn] == args[2]
+		}, map[int]driver.Value{publishedAtColumn: args[0], attemptsColumn: args[1]})
+	case strings.HasPrefix(query, "update outbox set attempts = ?, next_attempt_at = ?, last_error = ? where id = ?"):
+		return d.update(func(row []driver.Value) bool {
+			return row[idColumn] == args[3]
+		}, map[int]driver.Value{attemptsColumn: args[0], nextAttemptAtColumn: args[1], lastErrorColumn: args[2]})
+	case strings.HasPrefix(query, "update outbox set next_attempt_at = ? where id in ("):
+		return d.update(func(row []driver.Value) bool {
+			for _, id := range args[1:] {
+				if row[idColumn] == id {
+					return true
+				}
+			}
+			return false
+		}, map[int]driver.Value{nextAttemptAtColumn: args[0]})
+	case strings.HasPrefix(query, "update outbox set published_at = null"):
+		column := idColumn
+		if strings.HasSuffix(query, "where event_key = ?") {
+			column = keyColumn
+		}
+		return d.update(func(row []driver.Value) bool {
+			return row[column] == args[3]
+		}, map[int]driver.Value{publishedAtColumn: nil, attemptsColumn: args[0], nextAttemptAtColumn: args[1],
+			lastErrorColumn: args[2]})
+	default:
+		return nil, fmt.Errorf("unexpected statement %s", query)
+	}
+}
+
+func (d *outboxTable) update(matches func([]driver.Value) bool, values map[int]driver.Value) (driver.Result, error) {
+	updated := int64(0)
+	for _, row := range d.rows {
+		if matches(row) {
+			for column, value := range values {
+				row[column] = value
+			}
+			updated++
+		}
+	}
+	return driver.RowsAffected(updated), nil
+}
+
+func (d *outboxTable) query(_, query string, args []driver.Value) (driver.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !strings.HasPrefix(query, "select id, event_key, event_type, payload, created_at, published_at, "+
+		"attempts, next_attempt_at, last_error from outbox") {
+		return nil, fmt.Errorf("unexpected query %s", query)
+	}
+	var rows [][]driver.Value
+	claimed := make(map[interface{}]bool)
+	for _, row := range d.rows {
+		switch {
+		case strings.Contains(query, "published_at is null") && row[publishedAtColumn] != nil:
+			continue
+		case strings.Contains(query, "not exists"):
+			// only the oldest pending event of every key is claimed, and only once it is due
+			head := !claimed[row[keyColumn]]
+			claimed[row[keyColumn]] = true
+			if !head || row[nextAttemptAtColumn].(int64) > args[0].(int64) {
+				continue
+			}
+		case strings.Contains(query, "published_at is not null") && row[publishedAtColumn] == nil:
+			continue
+		case strings.Contains(query, "event_key = ?") && row[keyColumn] != args[0]:
+			continue
+		}
+		rows = append(rows, append([]driver.Value(nil), row...))
+	}
+	if strings.Contains(query, "order by id desc") {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if limit := int(args[len(args)-1].(int64)); len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return databasetest.NewRows([]string{"id", "event_key", "event_type", "payload", "created_at", "published_at",
+		"attempts", "next_attempt_at", "last_error"}, rows...), nil
+}
+
+// webhook records the events it accepted and fails the ones it is told to fail once
+type webhook struct {
+	mu       sync.Mutex
+	failOnce map[string]bool
+	received []string
+}
+
+func (w *webhook) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var event outbox.Event
+	if err := json.NewDecoder(request.Body).Decode(&event); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	name := fmt.Sprintf("%s:%s", event.Key, event.Type)
+	if w.failOnce[name] {
+		delete(w.failOnce, name)
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.received = append(w.received, name)
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+func (w *webhook) getReceived() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.received...)
+}
+
+var events = &outboxTable{}
+
+func initOutbox(t *testing.T, config outbox.Config) {
+	databasetest.Register("outbox", databasetest.Handlers{Exec: events.exec, Query: events.query})
+	events.reset()
+	assert.NoError(t, database.InitDatabase(context.Background(), database.Config{Driver: "outbox"}))
+	assert.NoError(t, outbox.InitOutbox(context.Background(), config))
+	t.Cleanup(func() {
+		assert.NoError(t, outbox.Close())
+		assert.NoError(t, database.Close())
+	})
+}
+
+func write(t *testing.T, key, eventType string) {
+	assert.NoError(t, database.InTransaction(context.Background(), nil, func(tx *sql.Tx) error {
+		return outbox.Write(context.Background(), tx, key, eventType, map[string]string{"key": key})
+	}))
+}
+
+func TestDisabledOutbox(t *testing.T) {
+	initOutbox(t, outbox.Config{})
+	write(t, "a", constants.CounterCreatedEvent)
+	list, err := outbox.List(context.Background(), outbox.Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	initOutbox(t, outbox.Config{Enabled: true, Sink: constants.FileOutboxSink, FilePath: path})
+	write(t, "a", constants.CounterCreatedEvent)
+	write(t, "a", constants.CounterIncrementedEvent)
+
+	published, err := outbox.RelayPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	var event outbox.Event
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, constants.CounterIncrementedEvent, event.Type)
+	assert.JSONEq(t, `{"key":"a"}`, string(event.Payload))
+
+	// nothing is published twice once it is marked published
+	published, err = outbox.RelayPending(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, published)
+}
+
+func TestHTTPSinkOrderingAndReplay(t *testing.T) {
+	hook := &webhook{failOnce: map[string]bool{"a:" + constants.CounterCreatedEvent: true}}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+	httpclient.Init(httpclient.NewRequestConfig(constants.OutboxRequestName, map[string]interface{}{
+		"method":          http.MethodPost,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		// the retries are left to the relay
+		"retrycount": 0,
+	}))
+	initOutbox(t, outbox.Config{Enabled: true, Sink: constants.HTTPOutboxSink})
+	ctx := context.Background()
+	write(t, "a", constants.CounterCreatedEvent)
+	write(t, "b", constants.CounterCreatedEvent)
+	write(t, "a", constants.CounterIncrementedEvent)
+
+	// the failed event holds back the later events for the same key only
+	published, err := outbox.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	pending, err := outbox.List(ctx, outbox.Filter{Status: constants.PendingOutboxStatus})
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, 1, pending[1].Attempts)
+	assert.Contains(t, pending[1].LastError, "503")
+
+	published, err = outbox.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []string{"b:" + constants.CounterCreatedEvent, "a:" + constants.CounterCreatedEvent,
+		"a:" + constants.CounterIncrementedEvent}, hook.getReceived())
+
+	// replaying the key publishes all its events again in order
+	replayed, err := outbox.Replay(ctx, 0, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), replayed)
+	published, err = outbox.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []string{"a:" + constants.CounterCreatedEvent, "a:" + constants.CounterIncrementedEvent},
+		hook.getReceived()[3:])
+
+	list, err := outbox.List(ctx, outbox.Filter{Key: "b", Status: constants.PublishedOutboxStatus, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.NotNil(t, list[0].PublishedAt)
+}
+
+func TestRelayClaimsTheDueEvents(t *testing.T) {
+	hook := &webhook{failOnce: map[string]bool{"a:" + constants.CounterCreatedEvent: true}}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+	httpclient.Init(httpclient.NewRequestConfig(constants.OutboxRequestName, map[string]interface{}{
+		"method":          http.MethodPost,
+		"url":             server.URL,
+		"timeoutinmillis": 1000,
+		"retrycount":      0,
+	}))
+	initOutbox(t, outbox.Config{Enabled: true, Sink: constants.HTTPOutboxSink, BatchSize: 1, Backoff: time.Hour})
+	ctx := context.Background()
+	write(t, "a", constants.CounterCreatedEvent)
+	write(t, "a", constants.CounterIncrementedEvent)
+	write(t, "b", constants.CounterCreatedEvent)
+
+	published, err := outbox.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, published)
+
+	// the failed event backing off neither fills the batch nor lets the later event for its key through
+	published, err = outbox.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	published, err = outbox.RelayPending(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, published)
+	assert.Equal(t, []string{"b:" + constants.CounterCreatedEvent}, hook.getReceived())
+}
+
+func TestInvalidOutboxConfig(t *testing.T) {
+	assert.Error(t, outbox.InitOutbox(context.Background(), outbox.Config{Enabled: true, Sink: "kafka"}))
+	_, err := outbox.Replay(context.Background(), 0, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/metrics"
+	"strings"
+	"sync"
+	"time"
+)
+
+// relayer publishes the pending events in the order they were written
+// An event is marked published only after the sink accepts it, so a crash in between publishes it again.
+// Only the oldest pending event of every key is claimed at a time, so when an event fails the later events
+// for the same key wait for it to be published, and the relays of other instances never publish them side by side.
+type relayer struct {
+	sink   sink
+	config Config
+	mu     sync.Mutex
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+var relay *relayer
+
+func startRelay(s sink, c Config) *relayer {
+	r := &relayer{sink: s, config: c, stop: make(chan struct{})}
+	if c.PollInterval > 0 {
+		r.wg.Add(1)
+		go r.run()
+	}
+	return r
+}
+
+// RelayPending is used to publish the pending events which are due right away
+// It returns the number of events published.
+func RelayPending(ctx context.Context) (int, error) {
+	if relay == nil {
+		return 0, nil
+	}
+	return relay.relayPending(ctx)
+}
+
+func (r *relayer) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if !database.IsAvailable() {
+				continue
+			}
+			if _, err := r.relayPending(context.Background()); err != nil {
+				log.Error(context.Background()).Err(err).Msg("error relaying outbox events")
+			}
+		}
+	}
+}
+
+func (r *relayer) relayPending(ctx context.Context) (int, error) {
+	// the poller and the explicit calls never publish the same batch side by side
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	published, attempted := 0, 0
+	for attempted < r.getBatchSize() {
+		events, err := r.claim(ctx, r.getBatchSize()-attempted)
+		if err != nil || len(events) == 0 {
+			return published, err
+		}
+		attempted += len(events)
+		failed := false
+		for _, event := range events {
+			if err = r.sink.Publish(ctx, event); err != nil {
+				failed = true
+				metrics.GetCounter(constants.OutboxFailuresMetric).Inc()
+				log.Warn(ctx).Err(err).Int64(constants.OutboxEventIDKey, event.ID).
+					Str(constants.OutboxEventKeyKey, event.Key).
+					Msgf("error publishing outbox event, attempt %d", event.Attempts+1)
+				if err = r.markFailed(ctx, event, err); err != nil {
+					return published, err
+				}
+				continue
+			}
+			metrics.GetCounter(constants.OutboxPublishedMetric).Inc()
+			if err = r.markPublished(ctx, event); err != nil {
+				return published, err
+			}
+			published++
+		}
+		if failed {
+			// the failed events are left for the next poll instead of being retried right away
+			return published, nil
+		}
+	}
+	return published, nil
+}
+
+// claim is used to lease the oldest pending event of every key which is due
+// The events locked by another instance are skipped, and the lease keeps them from being claimed again
+// once the transaction ends until they are either published or failed.
+func (r *relayer) claim(ctx context.Context, limit int) ([]Event, error) {
+	var events []Event
+	err := database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		now := time.Now()
+		var err error
+		events, err = queryEvents(database.WithQueryName(ctx, "claimOutboxEvents"), tx,
+			database.Rebind(database.LockForUpdate("select id, event_key, event_type, payload, created_at, "+
+				"published_at, attempts, next_attempt_at, last_error from outbox o where published_at is null and "+
+				"next_attempt_at <= ? and not exists (select 1 from outbox p where p.event_key = o.event_key and "+
+				"p.published_at is null and p.id < o.id) order by id limit ?")), toMillis(now), limit)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		args := make([]interface{}, 0, len(events)+1)
+		args = append(args, toMillis(now.Add(constants.OutboxClaimLease)))
+		for _, event := range events {
+			args = append(args, event.ID)
+		}
+		_, err = tx.ExecContext(database.WithQueryName(ctx, "leaseOutboxEvents"),
+			database.Rebind("update outbox set next_attempt_at = ? where id in ("+
+				strings.TrimSuffix(strings.Repeat("?, ", len(events)), ", ")+")"), args...)
+		return err
+	})
+	return events, err
+}
+
+func (r *relayer) markPublished(ctx context.Context, event Event) error {
+	return database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(database.WithQueryName(ctx, "markOutboxEventPublished"),
+			database.Rebind("update outbox set published_at = ?, attempts = ? where id = ?"),
+			toMillis(time.Now()), event.Attempts+1, event.ID)
+		return err
+	})
+}
+
+func (r *relayer) markFailed(ctx context.Context, event Event, cause error) error {
+	return database.InTransaction(ctx, nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(database.WithQueryName(ctx, "markOutboxEventFailed"),
+			database.Rebind("update outbox set attempts = ?, next_attempt_at = ?, last_error = ? where id = ?"),
+			event.Attempts+1, toMillis(time.Now().Add(r.getBackoff(event.Attempts+1))), cause.Error(), event.ID)
+		return err
+	})
+}
+
+func (r *relayer) getBatchSize() int {
+	if r.config.BatchSize <= 0 {
+		return constants.DefaultOutboxBatchSize
+	}
+	return r.config.BatchSize
+}
+
+// getBackoff doubles the backoff for every failed attempt, capped at the maximum backoff
+func (r *relayer) getBackoff(attempts int) time.Duration {
+	backoff := r.config.Backoff
+	for i := 1; i < attempts && i < constants.MaxOutboxBackoffDoublings; i++ {
+		backoff *= 2
+		if r.config.MaxBackoff > 0 && backoff >= r.config.MaxBackoff {
+			return r.config.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+func (r *relayer) close() error {
+	close(r.stop)
+	r.wg.Wait()
+	return r.sink.Close()
+}
@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// sink is where the relay publishes the events to
+type sink interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+func newSink(c Config) (sink, error) {
+	switch c.Sink {
+	case constants.HTTPOutboxSink:
+		return httpSink{}, nil
+	case constants.FileOutboxSink:
+		if c.FilePath == "" {
+			return &fileSink{writer: os.Stdout}, nil
+		}
+		file, err := os.OpenFile(c.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return &fileSink{writer: file, closer: file}, nil
+	default:
+		return nil, fmt.Errorf("invalid outbox sink %s provided", c.Sink)
+	}
+}
+
+// httpSink posts the events to the outbox request of the http client
+// The event id is sent along so that the receiver can drop the events delivered more than once.
+type httpSink struct{}
+
+func (httpSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	response, err := httpclient.Get().Request(httpclient.NewRequest(constants.OutboxRequestName).
+		SetContext(ctx).
+		SetHeaderParam(constants.ContentTypeHeader, constants.JSONContentType).
+		SetHeaderParam(constants.EventIDHeader, strconv.FormatInt(event.ID, 10)).
+		SetBody(bytes.NewReader(body)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("outbox webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+func (httpSink) Close() error {
+	return nil
+}
+
+// fileSink writes the events as json lines, meant for local testing
+type fileSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+func (s *fileSink) Publish(_ context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.writer.Write(append(line, '\n'))
+	return err
+}
+
+func (s *fileSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
@@ -0,0 +1,167 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	"github.com/sinhashubham95/go-example-project/utils/database"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a reload, the changed keys applied and the ones taking effect only after a restart
+type Result struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// reloadable are the keys applied without a restart, along with all the keys under them
+var reloadable = []string{
+	"logger.level",
+	"application.counter.queryTimeoutInMillis",
+	"application.admin.token",
+	"application.http",
+	"database.maxOpenConnections",
+	"database.maxIdleConnections",
+	"database.connectionMaxLifetimeInSeconds",
+	"database.connectionMaxIdleTimeInSeconds",
+}
+
+var mu sync.Mutex
+
+// Init is used to reload the configs whenever any of their files change
+func Init(ctx context.Context) error {
+	for _, name := range []string{constants.LoggerConfig, constants.ApplicationConfig, constants.DatabaseConfig} {
+		err := configs.AddChangeListener(name, func() {
+			_, _ = Reload(ctx)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reload is used to load the configs again, validate them and apply the changes to them
+// The invalid configs are rejected with all the problems found, keeping the last good ones, which GetConfig keeps
+// returning. The changes which cannot be applied without a restart are only reported, even though they are seen in
+// the typed configs got afterwards.
+func Reload(ctx context.Context) (Result, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, warnings, err := configs.Load()
+	if err != nil {
+		log.Error(ctx).Err(err).Msg("invalid configs rejected, keeping the last good ones")
+		return Result{}, err
+	}
+	for _, key := range warnings {
+		log.Warn(ctx).Str(constants.ConfigKeyKey, key).Msg("unknown config key")
+	}
+
+	previous := configs.GetConfig()
+	result := Result{Applied: []string{}, RestartRequired: []string{}}
+	for _, key := range getChanges(previous, c) {
+		if isReloadable(key) {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+	apply(previous, c)
+
+	if len(result.Applied) > 0 {
+		log.Info(ctx).Strs(constants.AppliedKey, result.Applied).Msg("configs reloaded")
+	}
+	if len(result.RestartRequired) > 0 {
+		log.Warn(ctx).Strs(constants.RestartRequiredKey, result.RestartRequired).
+			Msg("config changes take effect only after a restart")
+	}
+	return result, nil
+}
+
+// apply is used to apply the reloadable configs, the counter timeouts being applied by setting them
+// The upstreams are made from the typed configs loaded, never from the files, which may have changed since.
+func apply(previous, c *configs.Config) {
+	if previous.Logger.Level != c.Logger.Level {
+		log.InitLogger(log.Level(c.Logger.Level))
+	}
+	if !reflect.DeepEqual(previous.Application.HTTP, c.Application.HTTP) {
+		httpclient.Init(httpclient.NewRequestConfigs(c.Application.GetRequests())...)
+	}
+	database.ConfigurePool(database.Config{
+		MaxOpenConnections:    c.Database.MaxOpenConnections,
+		MaxIdleConnections:    c.Database.MaxIdleConnections,
+		ConnectionMaxLifetime: time.Duration(c.Database.ConnectionMaxLifetimeInSeconds) * time.Second,
+		ConnectionMaxIdleTime: time.Duration(c.Database.ConnectionMaxIdleTimeInSeconds) * time.Second,
+	})
+	configs.SetConfig(c)
+}
+
+func isReloadable(key string) bool {
+	for _, r := range reloadable {
+		if key == r || strings.HasPrefix(key, r+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// getChanges is used to get the leaf keys of the configs with different values, sorted
+func getChanges(previous, c *configs.Config) []string {
+	var changes []string
+	for _, part := range []struct {
+		name           string
+		previous, next interface{}
+	}{
+		{name: constants.LoggerConfig, previous: previous.Logger, next: c.Logger},
+		{name: constants.ApplicationConfig, previous: previous.Application, next: c.Application},
+		{name: constants.DatabaseConfig, previous: previous.Database, next: c.Database},
+	} {
+		p, n := make(map[string]interface{}), make(map[string]interface{})
+		flatten(part.name, part.previous, p)
+		flatten(part.name, part.next, n)
+		for key, value := range n {
+			if !reflect.DeepEqual(p[key], value) {
+				changes = append(changes, key)
+			}
+		}
+		for key := range p {
+			if _, ok := n[key]; !ok {
+				changes = append(changes, key)
+			}
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// flatten is used to get the leaf keys of the value as they are in the config files, along with their values
+func flatten(prefix string, value interface{}, flat map[string]interface{}) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		flat[prefix] = value
+		return
+	}
+	// the empty maps have no leaf keys, same as the missing ones
+	var m map[interface{}]interface{}
+	if err = yaml.Unmarshal(data, &m); err != nil {
+		flat[prefix] = value
+		return
+	}
+	for k, v := range m {
+		key := prefix + "." + fmt.Sprint(k)
+		if _, ok := v.(map[interface{}]interface{}); ok {
+			flatten(key, v, flat)
+			continue
+		}
+		flat[key] = v
+	}
+}
@@ -0,0 +1,67 @@
+package reload_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/angel-one/go-utils/constants"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/utils/configs"
+	"github.com/sinhashubham95/go-example-project/utils/httpclient"
+	"github.com/sinhashubham95/go-example-project/utils/reload"
+	"github.com/stretchr/testify/assert"
+)
+
+func write(t *testing.T, directory, name, content string) {
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, name+".yml"), []byte(content), 0644))
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	write(t, directory, "logger", "level: info\n")
+	write(t, directory, "application", "counter:\n  queryTimeoutInMillis: 1000\n")
+	write(t, directory, "database", "dialect: sqlite\nname: counter\n")
+	assert.NoError(t, configs.Init(directory, "", "logger", "application", "database"))
+	c, _, err := configs.Load()
+	assert.NoError(t, err)
+	configs.SetConfig(c)
+
+	// the reloadable changes are applied and the rest are only reported
+	write(t, directory, "logger", "level: error\n")
+	write(t, directory, "application",
+		"counter:\n  queryTimeoutInMillis: 200\nhttp:\n  moxy:\n    method: GET\n    url: http://localhost\n")
+	write(t, directory, "database", "dialect: sqlite\nname: counters\nmaxOpenConnections: 5\n")
+	assert.NoError(t, configs.Init(directory, "", "logger", "application", "database"))
+	result, err := reload.Reload(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, reload.Result{
+		Applied: []string{"application.counter.queryTimeoutInMillis", "application.http.moxy.method",
+			"application.http.moxy.url", "database.maxOpenConnections", "logger.level"},
+		RestartRequired: []string{"database.name"},
+	}, result)
+	assert.False(t, log.Warn(ctx).Enabled())
+	assert.True(t, log.Error(ctx).Enabled())
+	assert.Equal(t, 200, configs.GetConfig().Application.Counter.QueryTimeoutInMillis)
+	_, err = httpclient.GetCircuit("moxy")
+	assert.NoError(t, err)
+
+	// nothing changes when the configs are the same
+	result, err = reload.Reload(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.RestartRequired)
+
+	// the invalid configs are rejected, keeping the last good ones
+	write(t, directory, "logger", "level: loud\n")
+	write(t, directory, "application", "counter:\n  queryTimeoutInMillis: 100\n")
+	assert.NoError(t, configs.Init(directory, "", "logger", "application", "database"))
+	_, err = reload.Reload(ctx)
+	var validationErr *configs.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.False(t, log.Warn(ctx).Enabled())
+	assert.Equal(t, 200, configs.GetConfig().Application.Counter.QueryTimeoutInMillis)
+	log.InitLogger(constants.TraceLevel)
+}
@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Config is the configuration for resolving the secret references
+type Config struct {
+	// KeyFile holds the base64 encoded AES key used to decrypt the encrypted references
+	KeyFile string `json:"keyFile"`
+}
+
+// ErrNoKey is returned when an encrypted reference is resolved without a key file configured
+var ErrNoKey = errors.New("no key file configured for encrypted secrets")
+
+var config Config
+
+// InitSecrets is used to initialize the secrets
+func InitSecrets(ctx context.Context, c Config) error {
+	log.Info(ctx).Interface(constants.SecretsConfigKey, c).Msg("initializing secrets")
+	if c.KeyFile != "" {
+		// fail fast on an unusable key rather than on the first encrypted reference
+		if _, err := readKey(c.KeyFile); err != nil {
+			return err
+		}
+	}
+	config = c
+	return nil
+}
+
+// Resolve is used to get the value of a secret reference
+// The references are of the form env:NAME, file:/path/to/secret or enc:<base64 ciphertext>,
+// and any other value is taken as it is.
+func Resolve(reference string) (string, error) {
+	switch {
+	case strings.HasPrefix(reference, constants.EnvSecretPrefix):
+		name := strings.TrimPrefix(reference, constants.EnvSecretPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret environment variable %s not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(reference, constants.FileSecretPrefix):
+		path := strings.TrimPrefix(reference, constants.FileSecretPrefix)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading secret file %s: %w", path, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(reference, constants.EncryptedSecretPrefix):
+		if config.KeyFile == "" {
+			return "", ErrNoKey
+		}
+		key, err := readKey(config.KeyFile)
+		if err != nil {
+			return "", err
+		}
+		return decrypt(key, strings.TrimPrefix(reference, constants.EncryptedSecretPrefix))
+	default:
+		return reference, nil
+	}
+}
+
+// Encrypt is used to get the encrypted reference for the value using the key in the key file
+func Encrypt(keyFile, value string) (string, error) {
+	key, err := readKey(keyFile)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return constants.EncryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Files is used to get the files the references are read from, so they can be watched for rotation
+func Files(references ...string) []string {
+	files := make([]string, 0, len(references))
+	encrypted := false
+	for _, reference := range references {
+		switch {
+		case strings.HasPrefix(reference, constants.FileSecretPrefix):
+			files = append(files, strings.TrimPrefix(reference, constants.FileSecretPrefix))
+		case strings.HasPrefix(reference, constants.EncryptedSecretPrefix):
+			encrypted = true
+		}
+	}
+	if encrypted && config.KeyFile != "" {
+		files = append(files, config.KeyFile)
+	}
+	return files
+}
+
+func readKey(keyFile string) ([]byte, error) {
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secrets key file %s: %w", keyFile, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key in %s: %w", keyFile, err)
+	}
+	if _, err = aes.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("invalid secrets key in %s: %w", keyFile, err)
+	}
+	return key, nil
+}
+
+func decrypt(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted secret: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted secret: too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting secret: %w", err)
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
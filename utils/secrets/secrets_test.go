@@ -0,0 +1,99 @@
+package secrets_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/utils/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func TestResolvePlain(t *testing.T) {
+	value, err := secrets.Resolve("password")
+	assert.NoError(t, err)
+	assert.Equal(t, "password", value)
+}
+
+func TestResolveEnv(t *testing.T) {
+	assert.NoError(t, os.Setenv("SECRETS_TEST_PASSWORD", "from-env"))
+	defer func() {
+		_ = os.Unsetenv("SECRETS_TEST_PASSWORD")
+	}()
+	value, err := secrets.Resolve("env:SECRETS_TEST_PASSWORD")
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	_, err = secrets.Resolve("env:SECRETS_TEST_MISSING")
+	assert.Error(t, err)
+}
+
+func TestResolveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	writeFile(t, path, "from-file\n")
+	value, err := secrets.Resolve("file:" + path)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+
+	_, err = secrets.Resolve("file:" + path + ".missing")
+	assert.Error(t, err)
+}
+
+func TestResolveEncrypted(t *testing.T) {
+	assert.NoError(t, secrets.InitSecrets(context.Background(), secrets.Config{}))
+	_, err := secrets.Resolve("enc:abc")
+	assert.Equal(t, secrets.ErrNoKey, err)
+
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	assert.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "key")
+	writeFile(t, keyFile, base64.StdEncoding.EncodeToString(key))
+	assert.NoError(t, secrets.InitSecrets(context.Background(), secrets.Config{KeyFile: keyFile}))
+	defer func() {
+		assert.NoError(t, secrets.InitSecrets(context.Background(), secrets.Config{}))
+	}()
+
+	reference, err := secrets.Encrypt(keyFile, "from-key")
+	assert.NoError(t, err)
+	value, err := secrets.Resolve(reference)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-key", value)
+
+	_, err = secrets.Resolve(reference[:len(reference)-4] + "AAAA")
+	assert.Error(t, err)
+	assert.Equal(t, []string{keyFile}, secrets.Files("plain", reference))
+}
+
+func TestInvalidKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	writeFile(t, keyFile, base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, secrets.InitSecrets(context.Background(), secrets.Config{KeyFile: keyFile}))
+}
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	writeFile(t, path, "first")
+	var changes int32
+	w := secrets.NewWatcher(5*time.Millisecond, func() {
+		atomic.AddInt32(&changes, 1)
+	}, secrets.Files("file:"+path)...)
+	defer w.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, atomic.LoadInt32(&changes))
+	writeFile(t, path, "second")
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&changes) == 1
+	}, time.Second, 5*time.Millisecond)
+}
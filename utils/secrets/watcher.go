@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"github.com/angel-one/go-utils/log"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// Watcher polls the secret files and calls back when any of them changes
+// Polling the content rather than watching for events also catches the symlink swaps used by mounted secrets.
+type Watcher struct {
+	files    []string
+	sums     map[string][sha256.Size]byte
+	onChange func()
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewWatcher is used to start watching the files every interval
+func NewWatcher(interval time.Duration, onChange func(), files ...string) *Watcher {
+	w := &Watcher{
+		files:    files,
+		sums:     make(map[string][sha256.Size]byte, len(files)),
+		onChange: onChange,
+		stop:     make(chan struct{}),
+	}
+	w.changed()
+	if interval > 0 && len(files) > 0 {
+		w.wg.Add(1)
+		go w.run(interval)
+	}
+	return w
+}
+
+func (w *Watcher) run(interval time.Duration) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if w.changed() {
+				log.Info(context.Background()).Strs(constants.FilesKey, w.files).Msg("secrets rotated")
+				w.onChange()
+			}
+		}
+	}
+}
+
+// changed records the current checksums, a file which cannot be read keeps its last checksum
+func (w *Watcher) changed() bool {
+	changed := false
+	for _, file := range w.files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Error(context.Background()).Err(err).Msgf("error reading secret file %s", file)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if previous, ok := w.sums[file]; ok && previous != sum {
+			changed = true
+		}
+		w.sums[file] = sum
+	}
+	return changed
+}
+
+// Close is used to stop watching the files
+func (w *Watcher) Close() {
+	close(w.stop)
+	w.wg.Wait()
+}
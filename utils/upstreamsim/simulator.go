@@ -0,0 +1,290 @@
+package upstreamsim
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Simulator serves the fake endpoints of a spec
+type Simulator struct {
+	endpoints []*endpoint
+	mu        sync.Mutex
+	random    *rand.Rand
+}
+
+// Server is a simulator serving on a local port, for the tests
+type Server struct {
+	*httptest.Server
+	*Simulator
+}
+
+type endpoint struct {
+	name      string
+	method    string
+	segments  []string
+	response  *response
+	sequence  []*response
+	latency   Latency
+	errorRate float64
+	err       *response
+	calls     int64
+}
+
+type response struct {
+	status  int
+	headers map[string]string
+	body    *template.Template
+}
+
+// request is what the templates of the response bodies are executed with
+type request struct {
+	Method  string
+	Path    string
+	Params  map[string]string
+	Query   map[string]string
+	Headers map[string]string
+	Body    string
+	Calls   int64
+}
+
+// New is used to create a new simulator serving the endpoints of the spec
+func New(spec Spec) (*Simulator, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	seed := spec.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	s := &Simulator{endpoints: make([]*endpoint, 0, len(spec.Endpoints)), random: rand.New(rand.NewSource(seed))}
+	for _, e := range spec.Endpoints {
+		ep, err := newEndpoint(e)
+		if err != nil {
+			return nil, err
+		}
+		s.endpoints = append(s.endpoints, ep)
+	}
+	return s, nil
+}
+
+// NewServer is used to start a simulator serving the endpoints of the spec on a local port
+// The url of the server is what the upstream urls are pointed at, and it is closed once done.
+func NewServer(spec Spec) (*Server, error) {
+	s, err := New(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{Server: httptest.NewServer(s), Simulator: s}, nil
+}
+
+// Calls is used to get the number of calls made to the endpoint with the name
+func (s *Simulator) Calls(name string) int64 {
+	for _, e := range s.endpoints {
+		if e.name == name {
+			return atomic.LoadInt64(&e.calls)
+		}
+	}
+	return 0
+}
+
+// Reset is used to forget the calls made to the endpoints, starting their sequences over
+func (s *Simulator) Reset() {
+	for _, e := range s.endpoints {
+		atomic.StoreInt64(&e.calls, 0)
+	}
+}
+
+func (s *Simulator) ServeHTTP(writer http.ResponseWriter, r *http.Request) {
+	e, params := s.match(r)
+	if e == nil {
+		http.Error(writer, fmt.Sprintf("no endpoint for %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+	calls := atomic.AddInt64(&e.calls, 1)
+	res, latency := s.pick(e, calls)
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+		return
+	}
+
+	if res.status < 0 {
+		drop(writer)
+		return
+	}
+	body, err := res.execute(newRequest(r, params, calls))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for name, value := range res.headers {
+		writer.Header().Set(name, value)
+	}
+	writer.WriteHeader(res.status)
+	_, _ = writer.Write(body)
+}
+
+func (s *Simulator) match(r *http.Request) (*endpoint, map[string]string) {
+	segments := split(r.URL.Path)
+	for _, e := range s.endpoints {
+		if e.method != "" && e.method != r.Method {
+			continue
+		}
+		if params, ok := e.match(segments); ok {
+			return e, params
+		}
+	}
+	return nil, nil
+}
+
+// pick is used to get the response for the call to the endpoint, along with how long to take for it
+func (s *Simulator) pick(e *endpoint, calls int64) (*response, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latency := s.sample(e.latency)
+	if e.errorRate > 0 && s.random.Float64() < e.errorRate {
+		return e.err, latency
+	}
+	if len(e.sequence) == 0 {
+		return e.response, latency
+	}
+	if calls > int64(len(e.sequence)) {
+		return e.sequence[len(e.sequence)-1], latency
+	}
+	return e.sequence[calls-1], latency
+}
+
+func (s *Simulator) sample(l Latency) time.Duration {
+	var millis float64
+	switch l.Distribution {
+	case constants.UniformLatencyDistribution:
+		millis = l.MinInMillis + s.random.Float64()*(l.MaxInMillis-l.MinInMillis)
+	case constants.NormalLatencyDistribution:
+		millis = l.MeanInMillis + s.random.NormFloat64()*l.StdDevInMillis
+	default:
+		millis = l.FixedInMillis
+	}
+	if millis < 0 {
+		return 0
+	}
+	return time.Duration(millis * float64(time.Millisecond))
+}
+
+func newEndpoint(e Endpoint) (*endpoint, error) {
+	ep := &endpoint{
+		name:      e.getName(),
+		method:    strings.ToUpper(e.Method),
+		segments:  split(e.Path),
+		latency:   e.Latency,
+		errorRate: e.ErrorRate,
+	}
+	var err error
+	if ep.response, err = newResponse(ep.name, e.Response); err != nil {
+		return nil, err
+	}
+	for _, r := range e.Sequence {
+		res, err := newResponse(ep.name, r)
+		if err != nil {
+			return nil, err
+		}
+		ep.sequence = append(ep.sequence, res)
+	}
+	errorResponse := Response{Status: http.StatusInternalServerError, Body: "simulated error"}
+	if e.Error != nil {
+		errorResponse = *e.Error
+	}
+	if ep.err, err = newResponse(ep.name, errorResponse); err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
+
+func (e *endpoint) match(segments []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i, segment := range e.segments {
+		if segment == "*" && i == len(e.segments)-1 {
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			params[strings.Trim(segment, "{}")] = segments[i]
+		case segment != segments[i]:
+			return nil, false
+		}
+	}
+	return params, len(segments) == len(e.segments)
+}
+
+func newResponse(name string, r Response) (*response, error) {
+	body, err := template.New(name).Parse(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the body of the endpoint %s: %w", name, err)
+	}
+	return &response{status: r.getStatus(), headers: r.Headers, body: body}, nil
+}
+
+func (r *response) execute(req request) ([]byte, error) {
+	var body bytes.Buffer
+	if err := r.body.Execute(&body, req); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func newRequest(r *http.Request, params map[string]string, calls int64) request {
+	req := request{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Params:  params,
+		Query:   make(map[string]string),
+		Headers: make(map[string]string),
+		Calls:   calls,
+	}
+	for name := range r.URL.Query() {
+		req.Query[name] = r.URL.Query().Get(name)
+	}
+	for name := range r.Header {
+		req.Headers[name] = r.Header.Get(name)
+	}
+	if body, err := ioutil.ReadAll(r.Body); err == nil {
+		req.Body = string(body)
+	}
+	return req
+}
+
+// drop closes the connection without a response, like an upstream going away
+func drop(writer http.ResponseWriter) {
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	_ = conn.Close()
+}
+
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
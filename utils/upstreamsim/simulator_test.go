@@ -0,0 +1,101 @@
+package upstreamsim_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinhashubham95/go-example-project/utils/upstreamsim"
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, method, url string) (int, string) {
+	request, err := http.NewRequest(method, url, strings.NewReader("payload"))
+	assert.NoError(t, err)
+	// the dropped connections would have the requests retried on a new one, were they reused
+	request.Close = true
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	return response.StatusCode, string(body)
+}
+
+func TestLoad(t *testing.T) {
+	spec, err := upstreamsim.Load("../../resources/upstream-sim.yml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, spec.Endpoints)
+
+	assert.Error(t, upstreamsim.Spec{}.Validate())
+	assert.Error(t, upstreamsim.Spec{Endpoints: []upstreamsim.Endpoint{{Path: "/", ErrorRate: 2}}}.Validate())
+	assert.Error(t, upstreamsim.Spec{Endpoints: []upstreamsim.Endpoint{{Path: "/",
+		Latency: upstreamsim.Latency{Distribution: "uniform", MinInMillis: 2, MaxInMillis: 1}}}}.Validate())
+}
+
+func TestServer(t *testing.T) {
+	server, err := upstreamsim.NewServer(upstreamsim.Spec{Endpoints: []upstreamsim.Endpoint{
+		{
+			Name:     "echo",
+			Method:   http.MethodPost,
+			Path:     "/echo/{key}/*",
+			Response: upstreamsim.Response{Body: "{{.Params.key}} {{.Query.a}} {{.Body}} {{.Calls}}"},
+		},
+		{
+			Name:     "sequence",
+			Path:     "/sequence",
+			Sequence: []upstreamsim.Response{{Status: http.StatusServiceUnavailable}, {Status: -1}, {Body: "ok"}},
+		},
+	}})
+	assert.NoError(t, err)
+	defer server.Close()
+
+	status, body := get(t, http.MethodPost, server.URL+"/echo/k/a/b?a=b")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "k b payload 1", body)
+	status, _ = get(t, http.MethodGet, server.URL+"/echo/k/a")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = get(t, http.MethodGet, server.URL+"/sequence")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	status, _ = get(t, http.MethodGet, server.URL+"/sequence")
+	assert.Equal(t, 0, status)
+	for i := 0; i < 2; i++ {
+		status, body = get(t, http.MethodGet, server.URL+"/sequence")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "ok", body)
+	}
+	assert.Equal(t, int64(4), server.Calls("sequence"))
+
+	server.Reset()
+	status, _ = get(t, http.MethodGet, server.URL+"/sequence")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestLatencyAndErrors(t *testing.T) {
+	server, err := upstreamsim.NewServer(upstreamsim.Spec{Seed: 1, Endpoints: []upstreamsim.Endpoint{{
+		Path:      "/",
+		Latency:   upstreamsim.Latency{Distribution: "uniform", MinInMillis: 20, MaxInMillis: 30},
+		ErrorRate: 0.5,
+		Error:     &upstreamsim.Response{Status: http.StatusBadGateway},
+	}}})
+	assert.NoError(t, err)
+	defer server.Close()
+
+	failures := 0
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if status, _ := get(t, http.MethodGet, server.URL); status == http.StatusBadGateway {
+			failures++
+		}
+	}
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(20*20*time.Millisecond))
+	assert.True(t, failures > 0 && failures < 20)
+	assert.Equal(t, int64(20), server.Calls("* /"))
+}
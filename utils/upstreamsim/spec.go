@@ -0,0 +1,134 @@
+package upstreamsim
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sinhashubham95/go-example-project/constants"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Spec is the specification of the fake endpoints served by the simulator
+type Spec struct {
+	// Seed makes the latencies and the errors the same on every run, they are random when it is zero
+	Seed      int64      `yaml:"seed"`
+	Endpoints []Endpoint `yaml:"endpoints"`
+}
+
+// Endpoint is a fake endpoint, serving the requests matching its method and path
+type Endpoint struct {
+	// Name is used to count the calls made to the endpoint, the method and the path by default
+	Name string `yaml:"name"`
+	// Method is any of them when empty
+	Method string `yaml:"method"`
+	// Path can have params like /counters/{key}, and end with /* to match everything below it
+	Path string `yaml:"path"`
+	// Response is served when there is no sequence, or it is over
+	Response Response `yaml:"response"`
+	// Sequence is the responses served one after the other for the calls, the last one repeating after that
+	Sequence []Response `yaml:"sequence"`
+	Latency  Latency    `yaml:"latency"`
+	// ErrorRate is the fraction of the calls, between 0 and 1, served the error response instead
+	ErrorRate float64   `yaml:"errorRate"`
+	Error     *Response `yaml:"error"`
+}
+
+// Response is a fake response
+// The body is a template, executed with the method, path, params, query, headers and body of the request,
+// and the number of calls made to the endpoint.
+type Response struct {
+	// Status is 200 when not set, a negative status drops the connection without a response
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
+// Latency is how long the endpoint takes to respond
+// The distribution is one of fixed, taking the fixed millis, uniform, between the min and the max millis,
+// or normal, around the mean millis with the standard deviation.
+type Latency struct {
+	Distribution   string  `yaml:"distribution"`
+	FixedInMillis  float64 `yaml:"fixedInMillis"`
+	MinInMillis    float64 `yaml:"minInMillis"`
+	MaxInMillis    float64 `yaml:"maxInMillis"`
+	MeanInMillis   float64 `yaml:"meanInMillis"`
+	StdDevInMillis float64 `yaml:"stdDevInMillis"`
+}
+
+// Load is used to load the spec from the yaml file
+func Load(path string) (Spec, error) {
+	var spec Spec
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+	if err = yaml.UnmarshalStrict(data, &spec); err != nil {
+		return spec, fmt.Errorf("error decoding the spec %s: %w", path, err)
+	}
+	return spec, spec.Validate()
+}
+
+// Validate is used to validate the spec
+func (s Spec) Validate() error {
+	if len(s.Endpoints) == 0 {
+		return errors.New("endpoints cannot be empty")
+	}
+	for i, endpoint := range s.Endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return fmt.Errorf("invalid endpoint %d %s: %w", i, endpoint.getName(), err)
+		}
+	}
+	return nil
+}
+
+// Validate is used to validate the endpoint
+func (e Endpoint) Validate() error {
+	if !strings.HasPrefix(e.Path, "/") {
+		return errors.New("path should start with /")
+	}
+	if e.ErrorRate < 0 || e.ErrorRate > 1 {
+		return errors.New("error rate should be between 0 and 1")
+	}
+	return e.Latency.Validate()
+}
+
+// Validate is used to validate the latency
+func (l Latency) Validate() error {
+	switch l.Distribution {
+	case "", constants.FixedLatencyDistribution:
+		if l.FixedInMillis < 0 {
+			return errors.New("fixed latency cannot be negative")
+		}
+	case constants.UniformLatencyDistribution:
+		if l.MinInMillis < 0 || l.MaxInMillis < l.MinInMillis {
+			return errors.New("uniform latency should have a max not less than its min, which cannot be negative")
+		}
+	case constants.NormalLatencyDistribution:
+		if l.MeanInMillis < 0 || l.StdDevInMillis < 0 {
+			return errors.New("normal latency cannot have a negative mean or standard deviation")
+		}
+	default:
+		return fmt.Errorf("unknown latency distribution %s", l.Distribution)
+	}
+	return nil
+}
+
+func (e Endpoint) getName() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	method := strings.ToUpper(e.Method)
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + e.Path
+}
+
+func (r Response) getStatus() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}